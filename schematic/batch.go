@@ -0,0 +1,71 @@
+package schematic
+
+import (
+	"fmt"
+	"github.com/Tnze/go-mc/level/block"
+)
+
+// Batch buffers blocks to be set in a Project, so parallel workers can each
+// fill their own batch without locking and merge it with Flush:
+//
+//	var wg sync.WaitGroup
+//	for _, chunk := range chunks {
+//		wg.Add(1)
+//		go func(c Box) {
+//			defer wg.Done()
+//			b := p.NewBatch()
+//			generate(b, c)
+//			b.Flush()
+//		}(chunk)
+//	}
+//	wg.Wait()
+//
+// A Batch itself must only be used by one goroutine at a time.
+type Batch struct {
+	p      *Project
+	writes []batchWrite
+}
+
+type batchWrite struct {
+	pos   [3]int
+	state BlockState
+}
+
+// NewBatch returns an empty batch of blocks for p.
+func (p *Project) NewBatch() *Batch {
+	return &Batch{p: p}
+}
+
+// SetBlock records the block to be set at x, y, z, it panics when the
+// position is out of the project like Project.SetBlock.
+func (b *Batch) SetBlock(x, y, z int, blk block.Block) {
+	if b.p.MetaData.EnclosingSize.outOfRange(x, y, z) {
+		panic(fmt.Sprintf("SetBlock out of range : enclosingSize: %v,Pos: %d, %d, %d", b.p.MetaData.EnclosingSize, x, y, z))
+	}
+	b.writes = append(b.writes, batchWrite{[3]int{x, y, z}, NewBlockState(blk)})
+}
+
+// Len returns the number of blocks waiting in the batch.
+func (b *Batch) Len() int {
+	return len(b.writes)
+}
+
+// Flush sets the buffered blocks in the project, in the order they were
+// recorded, and empties the batch. Batches may be flushed concurrently.
+func (b *Batch) Flush() {
+	if len(b.writes) == 0 {
+		return
+	}
+	ids := make(map[BlockState]int)
+	b.p.mu.Lock()
+	for _, w := range b.writes {
+		id, ok := ids[w.state]
+		if !ok {
+			id = b.p.palette.id(w.state)
+			ids[w.state] = id
+		}
+		b.p.setID(w.pos[0], w.pos[1], w.pos[2], id)
+	}
+	b.p.mu.Unlock()
+	b.writes = b.writes[:0]
+}
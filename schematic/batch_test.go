@@ -0,0 +1,170 @@
+package schematic
+
+import (
+	"github.com/Tnze/go-mc/level/block"
+	"sync"
+	"testing"
+)
+
+var concurrentBlocks = []block.Block{block.Stone{}, block.Dirt{}, block.OakPlanks{}, block.Glass{}, block.WhiteWool{},
+	block.RedWool{}, block.BlueWool{}, block.Sand{}, block.Gravel{}, block.Cobblestone{}, block.Andesite{}, block.Granite{},
+	block.Diorite{}, block.Deepslate{}, block.Tuff{}, block.Calcite{}, block.Basalt{Axis: block.Y}, block.Obsidian{}}
+
+func concurrentBlock(x, y, z int) block.Block {
+	return concurrentBlocks[(x*7+y*13+z)%len(concurrentBlocks)]
+}
+
+func checkConcurrent(t *testing.T, p *Project) {
+	total := 0
+	for x := 0; x < p.XRange(); x++ {
+		for y := 0; y < p.YRange(); y++ {
+			for z := 0; z < p.ZRange(); z++ {
+				if y%2 == 1 {
+					continue
+				}
+				total++
+				if p.GetBlock(x, y, z) != NewBlockState(concurrentBlock(x, y, z)) {
+					t.Fatalf("Error, block at %d, %d, %d: %v", x, y, z, p.GetBlock(x, y, z))
+				}
+			}
+		}
+	}
+	if int(p.MetaData.TotalBlocks) != total {
+		t.Fatalf("Error, total blocks: %d, want %d", p.MetaData.TotalBlocks, total)
+	}
+}
+
+// TestConcurrentSetBlock is meant to be run with -race, every worker writes
+// its own layers while the palette and storage grow.
+func TestConcurrentSetBlock(t *testing.T) {
+	p := NewProject("concurrent", 16, 16, 16)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for y := w * 2; y < w*2+2; y++ {
+				for x := 0; x < 16; x++ {
+					for z := 0; z < 16; z++ {
+						p.SetBlock(x, y, z, concurrentBlock(x, y, z))
+						_ = p.GetBlock(15-x, 15-y, 15-z)
+					}
+				}
+				if y%2 == 1 {
+					for x := 0; x < 16; x++ {
+						for z := 0; z < 16; z++ {
+							p.SetBlock(x, y, z, block.Air{})
+						}
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	checkConcurrent(t, p)
+}
+
+func TestConcurrentBatch(t *testing.T) {
+	p := NewProject("concurrent", 16, 16, 16)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			b := p.NewBatch()
+			y := w * 2
+			for x := 0; x < 16; x++ {
+				for z := 0; z < 16; z++ {
+					b.SetBlock(x, y, z, concurrentBlock(x, y, z))
+				}
+			}
+			if b.Len() != 256 {
+				t.Errorf("Error, batch length: %d", b.Len())
+			}
+			b.Flush()
+			// read while the other batches are flushed
+			p.ForEachNonAir(func(x, y, z int, s BlockState) {})
+			if b.Len() != 0 {
+				t.Errorf("Error, batch not empty after flush")
+			}
+		}(w)
+	}
+	wg.Wait()
+	checkConcurrent(t, p)
+}
+
+// TestConcurrentCompact sets blocks while the palette is compacted, every id
+// must be taken from the palette it is stored in.
+func TestConcurrentCompact(t *testing.T) {
+	p := NewProject("concurrent", 16, 16, 16)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			p.Compact()
+			_ = p.Palette()
+		}
+	}()
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			b := p.NewBatch()
+			for x := 0; x < 16; x++ {
+				for z := 0; z < 16; z++ {
+					p.SetBlock(x, w*2, z, concurrentBlock(x, w*2, z))
+					b.SetBlock(x, w*2+1, z, concurrentBlock(x, w*2, z))
+				}
+				b.Flush()
+			}
+		}(w)
+	}
+	wg.Wait()
+	<-done
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			for z := 0; z < 16; z++ {
+				if got, want := p.GetBlock(x, y, z), NewBlockState(concurrentBlock(x, y/2*2, z)); got != want {
+					t.Fatalf("Error, block at %d, %d, %d: %v, want %v", x, y, z, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestConcurrentEncode(t *testing.T) {
+	p := NewProject("concurrent", 16, 16, 16)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			n := p.Nbt()
+			for _, b := range n.Blocks {
+				if int(b.State) >= len(n.Palette) {
+					t.Errorf("Error, state %d of a palette of %d", b.State, len(n.Palette))
+					return
+				}
+			}
+			r := p.Litematic().Regions["concurrent"]
+			if len(r.BlockStatePalette) == 0 || len(r.BlockStates) == 0 {
+				t.Errorf("Error, empty region")
+				return
+			}
+		}
+	}()
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for x := 0; x < 16; x++ {
+				for z := 0; z < 16; z++ {
+					p.SetBlock(x, w*2, z, concurrentBlock(x, w*2, z))
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	<-done
+}
@@ -0,0 +1,196 @@
+package schematic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/Tnze/go-mc/nbt"
+	"io"
+	"sort"
+)
+
+// RawList is a list of NBT tags of one type. The nbt encoder writes the
+// elements of a []nbt.RawMessage as structs, RawList writes their data.
+type RawList []nbt.RawMessage
+
+func (l RawList) TagType() byte { return nbt.TagList }
+
+func (l RawList) MarshalNBT(w io.Writer) error {
+	t := byte(nbt.TagCompound)
+	if len(l) > 0 {
+		t = l[0].Type
+	}
+	if _, err := w.Write([]byte{t}); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, int32(len(l))); err != nil {
+		return err
+	}
+	for _, m := range l {
+		if m.Type != t {
+			return fmt.Errorf("list of tag type %d has an element of type %d", t, m.Type)
+		}
+		if _, err := w.Write(m.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetBlockEntity sets the block entity data of the block at x, y, z, such as
+// the items of a chest or the text of a sign. data is any value nbt can
+// encode as a compound, usually with an "id" like "minecraft:chest" and
+// without the position. Block entities stay when the block is changed but
+// are only written for blocks that aren't air.
+func (p *Project) SetBlockEntity(x, y, z int, data any) error {
+	m, err := rawMessage(data)
+	if err != nil {
+		return err
+	}
+	if m.Type != nbt.TagCompound {
+		return fmt.Errorf("block entity at %d, %d, %d is not a compound", x, y, z)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.blockEntities == nil {
+		p.blockEntities = make(map[Vec3D]nbt.RawMessage)
+	}
+	p.blockEntities[Vec3D{int32(x), int32(y), int32(z)}] = m
+	return nil
+}
+
+// RemoveBlockEntity removes the block entity of the block at x, y, z.
+func (p *Project) RemoveBlockEntity(x, y, z int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.blockEntities, Vec3D{int32(x), int32(y), int32(z)})
+}
+
+// BlockEntity returns the block entity data of the block at x, y, z, to be
+// decoded with its Unmarshal method.
+func (p *Project) BlockEntity(x, y, z int) (nbt.RawMessage, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	m, ok := p.blockEntities[Vec3D{int32(x), int32(y), int32(z)}]
+	return m, ok
+}
+
+// BlockEntities returns the positions of the block entities of blocks that
+// aren't air, sorted like the blocks are stored.
+func (p *Project) BlockEntities() []Vec3D {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.blockEntityPositions()
+}
+
+// blockEntityPositions is BlockEntities without locking p.
+func (p *Project) blockEntityPositions() []Vec3D {
+	var pos []Vec3D
+	for v := range p.blockEntities {
+		if p.palette.value(p.blocks.get(int(v.X), int(v.Y), int(v.Z))).Name != air {
+			pos = append(pos, v)
+		}
+	}
+	sort.Slice(pos, func(i, j int) bool {
+		a, b := pos[i], pos[j]
+		if a.Y != b.Y {
+			return a.Y < b.Y
+		}
+		if a.Z != b.Z {
+			return a.Z < b.Z
+		}
+		return a.X < b.X
+	})
+	return pos
+}
+
+// tileEntities returns the block entities with their position, as
+// Litematica stores them. Block entities that can't be encoded are left out.
+func (p *Project) tileEntities() RawList {
+	tiles := RawList{}
+	for _, v := range p.blockEntityPositions() {
+		v := v
+		if m, err := withPos(p.blockEntities[v], &v); err == nil {
+			tiles = append(tiles, m)
+		}
+	}
+	return tiles
+}
+
+// setTileEntities sets the block entities of p from Litematica tile entities.
+func (p *Project) setTileEntities(tiles []nbt.RawMessage) {
+	for _, m := range tiles {
+		var pos Vec3D
+		if m.Type != nbt.TagCompound || m.Unmarshal(&pos) != nil {
+			continue
+		}
+		if m, err := withPos(m, nil); err == nil {
+			_ = p.SetBlockEntity(int(pos.X), int(pos.Y), int(pos.Z), m)
+		}
+	}
+}
+
+// Entities returns the entities of the project.
+func (p *Project) Entities() []Entity {
+	return p.entity.entity
+}
+
+// rawMessage encodes v to NBT.
+func rawMessage(v any) (nbt.RawMessage, error) {
+	if m, ok := v.(nbt.RawMessage); ok {
+		return m, nil
+	}
+	data, err := nbt.Marshal(v)
+	if err != nil {
+		return nbt.RawMessage{}, err
+	}
+	var m nbt.RawMessage
+	err = nbt.Unmarshal(data, &m)
+	return m, err
+}
+
+// compoundFields splits the compound m into its fields.
+func compoundFields(m nbt.RawMessage) (map[string]nbt.RawMessage, error) {
+	fields := make(map[string]nbt.RawMessage)
+	if err := m.Unmarshal(&fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// compoundOf joins fields into a compound, sorted by name so the same fields
+// always give the same bytes.
+func compoundOf(fields map[string]nbt.RawMessage) nbt.RawMessage {
+	names := make([]string, 0, len(fields))
+	for n := range fields {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	for _, n := range names {
+		_ = writeTag(&buf, fields[n].Type, n)
+		buf.Write(fields[n].Data)
+	}
+	buf.WriteByte(nbt.TagEnd)
+	return nbt.RawMessage{Type: nbt.TagCompound, Data: buf.Bytes()}
+}
+
+// withPos returns the block entity m with the fields x, y and z Litematica
+// stores, or without them if pos is nil.
+func withPos(m nbt.RawMessage, pos *Vec3D) (nbt.RawMessage, error) {
+	fields, err := compoundFields(m)
+	if err != nil {
+		return m, err
+	}
+	delete(fields, "x")
+	delete(fields, "y")
+	delete(fields, "z")
+	if pos != nil {
+		for n, v := range map[string]int32{"x": pos.X, "y": pos.Y, "z": pos.Z} {
+			if fields[n], err = rawMessage(v); err != nil {
+				return m, err
+			}
+		}
+	}
+	return compoundOf(fields), nil
+}
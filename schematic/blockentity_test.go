@@ -0,0 +1,149 @@
+package schematic
+
+import (
+	"bytes"
+	"github.com/Tnze/go-mc/level/block"
+	"github.com/Tnze/go-mc/nbt"
+	"reflect"
+	"testing"
+)
+
+type testChest struct {
+	ID    string `nbt:"id"`
+	Items []testItem
+}
+
+type testItem struct {
+	Slot  int8
+	ID    string `nbt:"id"`
+	Count int8
+}
+
+func blockEntityProject(t *testing.T) *Project {
+	p := NewProject("chest", 4, 3, 5)
+	p.SetBlock(1, 0, 2, block.Chest{Facing: block.North})
+	p.SetBlock(3, 2, 4, block.Stone{})
+	chest := testChest{ID: "minecraft:chest", Items: []testItem{{0, "minecraft:diamond", 3}}}
+	if err := p.SetBlockEntity(1, 0, 2, chest); err != nil {
+		t.Fatalf("Error, set block entity: %v", err)
+	}
+	// a block entity without its block is dropped
+	if err := p.SetBlockEntity(0, 1, 0, chest); err != nil {
+		t.Fatalf("Error, set block entity: %v", err)
+	}
+	if err := p.SetBlockEntity(0, 0, 0, int32(1)); err == nil {
+		t.Fatalf("Error, block entity that isn't a compound accepted")
+	}
+	p.AddEntity(GlowItemFrame{Pos: []float64{2.5, 1.03125, 0.5}, Motion: []float64{0, 0, 0}, Rotation: []float32{0, 0}, Facing: 1})
+	m, err := rawMessage(struct {
+		ID  string `nbt:"id"`
+		Pos []float64
+	}{"minecraft:armor_stand", []float64{0.5, 1, 3.25}})
+	if err != nil {
+		t.Fatalf("Error, %v", err)
+	}
+	p.AddEntity(RawEntity{m})
+	return p
+}
+
+func checkBlockEntities(t *testing.T, q *Project) {
+	if pos := q.BlockEntities(); len(pos) != 1 || pos[0] != (Vec3D{1, 0, 2}) {
+		t.Fatalf("Error, block entities at %v", pos)
+	}
+	m, _ := q.BlockEntity(1, 0, 2)
+	var chest struct {
+		testChest
+		X *int32 `nbt:"x"`
+	}
+	if err := m.Unmarshal(&chest); err != nil {
+		t.Fatalf("Error, decode chest: %v", err)
+	}
+	if chest.ID != "minecraft:chest" || len(chest.Items) != 1 || chest.Items[0].ID != "minecraft:diamond" || chest.Items[0].Count != 3 {
+		t.Fatalf("Error, chest %+v", chest.testChest)
+	}
+	if chest.X != nil {
+		t.Fatalf("Error, block entity keeps its position")
+	}
+
+	e := q.Entities()
+	if len(e) != 2 {
+		t.Fatalf("Error, %d entities, want 2", len(e))
+	}
+	frame, ok := e[0].(GlowItemFrame)
+	if !ok || frame.Facing != 1 || len(frame.Pos) != 3 || frame.Pos[0] != 2.5 || frame.Pos[1] != 1.03125 {
+		t.Fatalf("Error, item frame %#v", e[0])
+	}
+	if e[1].ID() != "minecraft:armor_stand" {
+		t.Fatalf("Error, entity %q, want minecraft:armor_stand", e[1].ID())
+	}
+	if pos, err := entityPos(e[1]); err != nil || pos != [3]float64{0.5, 1, 3.25} {
+		t.Fatalf("Error, armor stand at %v: %v", pos, err)
+	}
+}
+
+func TestNbtBlockEntities(t *testing.T) {
+	p := blockEntityProject(t)
+	n := p.Nbt()
+	if len(n.Entities) != 2 {
+		t.Fatalf("Error, %d structure entities, want 2", len(n.Entities))
+	}
+	if e := n.Entities[0]; len(e.BlockPos) != 3 || e.BlockPos[0] != 2 || e.BlockPos[1] != 1 || e.BlockPos[2] != 0 || e.Nbt.Type != nbt.TagCompound {
+		t.Fatalf("Error, structure entity %+v", e)
+	}
+	var file bytes.Buffer
+	if err := n.Encode(&file); err != nil {
+		t.Fatalf("Error, encode: %v", err)
+	}
+	q, err := LoadFromNbt("chest.nbt", &file)
+	if err != nil {
+		t.Fatalf("Error, load: %v", err)
+	}
+	checkBlockEntities(t, q)
+}
+
+func TestLitematicBlockEntities(t *testing.T) {
+	p := blockEntityProject(t)
+	tiles := p.tileEntities()
+	if len(tiles) != 1 {
+		t.Fatalf("Error, %d tile entities, want 1", len(tiles))
+	}
+	var pos Vec3D
+	if err := tiles[0].Unmarshal(&pos); err != nil || pos != (Vec3D{1, 0, 2}) {
+		t.Fatalf("Error, tile entity at %v: %v", pos, err)
+	}
+	var file bytes.Buffer
+	if err := p.Encode(&file); err != nil {
+		t.Fatalf("Error, encode: %v", err)
+	}
+	q, err := LoadFromLitematic(&file)
+	if err != nil {
+		t.Fatalf("Error, load: %v", err)
+	}
+	checkBlockEntities(t, q)
+}
+
+func TestGlowItemFrameRoundTrip(t *testing.T) {
+	frame := GlowItemFrame{Pos: []float64{2.5, 1.03125, 0.5}, Motion: []float64{0, 0.5, 0}, Rotation: []float32{90, 0}, Facing: 1, UUID: []int32{1, 2, 3, 4}}
+	data, err := nbt.Marshal(frame)
+	if err != nil {
+		t.Fatalf("Error, marshal: %v", err)
+	}
+	var tags struct {
+		Motion, Pos, Rotation nbt.RawMessage
+	}
+	if err := nbt.Unmarshal(data, &tags); err != nil {
+		t.Fatalf("Error, unmarshal: %v", err)
+	}
+	for _, m := range []nbt.RawMessage{tags.Motion, tags.Pos, tags.Rotation} {
+		if m.Type != nbt.TagList {
+			t.Fatalf("Error, tag type %d, want a list", m.Type)
+		}
+	}
+	var back GlowItemFrame
+	if err := nbt.Unmarshal(data, &back); err != nil {
+		t.Fatalf("Error, unmarshal: %v", err)
+	}
+	if !reflect.DeepEqual(back, frame) {
+		t.Fatalf("Error, round trip gave %+v", back)
+	}
+}
@@ -0,0 +1,107 @@
+package schematic
+
+import "strings"
+
+// shortName returns the block ID without the minecraft namespace.
+func shortName(s BlockState) string {
+	return strings.TrimPrefix(s.Name, "minecraft:")
+}
+
+func isAirName(name string) bool {
+	return name == "air" || name == "cave_air" || name == "void_air"
+}
+
+func isFluid(name string) bool {
+	return name == "water" || name == "lava" || name == "bubble_column"
+}
+
+func isPlant(name string) bool {
+	return plantBlocks[name] || strings.HasSuffix(name, "_sapling") || strings.HasSuffix(name, "_tulip") ||
+		strings.HasSuffix(name, "_coral") || strings.HasSuffix(name, "_coral_fan")
+}
+
+// isGravityBlock reports whether the block falls when there is nothing below.
+func isGravityBlock(name string) bool {
+	switch name {
+	case "sand", "red_sand", "gravel", "suspicious_sand", "suspicious_gravel",
+		"anvil", "chipped_anvil", "damaged_anvil", "dragon_egg":
+		return true
+	}
+	return strings.HasSuffix(name, "_concrete_powder")
+}
+
+// floorBlocks stand on the block below them.
+var floorBlocks = map[string]bool{
+	"torch": true, "soul_torch": true, "redstone_torch": true, "redstone_wire": true,
+	"repeater": true, "comparator": true, "rail": true, "snow": true, "cake": true,
+	"candle": true, "sea_pickle": true, "lily_pad": true, "frogspawn": true,
+	"moss_carpet": true, "pink_petals": true, "nether_wart": true,
+}
+
+// hangingPlants hang from the block above them.
+var hangingPlants = map[string]bool{
+	"weeping_vines": true, "weeping_vines_plant": true, "cave_vines": true,
+	"cave_vines_plant": true, "hanging_roots": true, "spore_blossom": true,
+}
+
+// facingOffset returns the direction of a facing property.
+func facingOffset(facing string) Vec3D {
+	switch facing {
+	case "north":
+		return Vec3D{0, 0, -1}
+	case "south":
+		return Vec3D{0, 0, 1}
+	case "west":
+		return Vec3D{-1, 0, 0}
+	case "east":
+		return Vec3D{1, 0, 0}
+	case "up":
+		return Vec3D{0, 1, 0}
+	case "down":
+		return Vec3D{0, -1, 0}
+	}
+	return Vec3D{}
+}
+
+// supportOffset returns where the block the state s is attached to is,
+// relative to s, for blocks that break or fall without it.
+func supportOffset(s BlockState) (Vec3D, bool) {
+	name := shortName(s)
+	below, above := Vec3D{0, -1, 0}, Vec3D{0, 1, 0}
+	behind := func() Vec3D {
+		f := facingOffset(property(s.Properties, "facing"))
+		return Vec3D{-f.X, -f.Y, -f.Z}
+	}
+	switch {
+	case hangingPlants[name] || strings.HasSuffix(name, "_hanging_sign") && !strings.Contains(name, "_wall_"):
+		return above, true
+	case name == "lantern" || name == "soul_lantern":
+		if property(s.Properties, "hanging") == "true" {
+			return above, true
+		}
+		return below, true
+	case name == "lever" || strings.HasSuffix(name, "_button"):
+		switch property(s.Properties, "face") {
+		case "floor":
+			return below, true
+		case "ceiling":
+			return above, true
+		}
+		return behind(), true
+	case strings.HasSuffix(name, "wall_torch") || strings.HasSuffix(name, "_wall_sign") ||
+		strings.HasSuffix(name, "_wall_banner") || strings.HasSuffix(name, "_wall_fan") ||
+		name == "ladder" || name == "tripwire_hook":
+		return behind(), true
+	case name == "cocoa":
+		return facingOffset(property(s.Properties, "facing")), true
+	case name == "vine" || name == "glow_lichen" || name == "sculk_vein" || strings.HasSuffix(name, "_wall_hanging_sign"):
+		return Vec3D{}, false
+	case isGravityBlock(name) || floorBlocks[name] || isPlant(name) ||
+		strings.HasSuffix(name, "_door") || strings.HasSuffix(name, "_pressure_plate") ||
+		strings.HasSuffix(name, "_carpet") || strings.HasSuffix(name, "_rail") ||
+		strings.HasSuffix(name, "_sign") || strings.HasSuffix(name, "_banner") ||
+		strings.HasSuffix(name, "_candle"):
+		return below, true
+	}
+	return Vec3D{}, false
+}
@@ -0,0 +1,235 @@
+package schematic
+
+import (
+	"github.com/Tnze/go-mc/level/block"
+	"strconv"
+	"strings"
+)
+
+// box is an axis aligned box in block units, (0,0,0)-(1,1,1) is a full block.
+type box struct {
+	Min, Max Vec3
+}
+
+var fullCube = []box{{Vec3{0, 0, 0}, Vec3{1, 1, 1}}}
+
+func px(n float64) float64 { return n / 16 }
+
+func pxBox(x0, y0, z0, x1, y1, z1 float64) box {
+	return box{Vec3{px(x0), px(y0), px(z0)}, Vec3{px(x1), px(y1), px(z1)}}
+}
+
+// rotate turns a box defined for a block facing north around the Y axis.
+func (b box) rotate(facing string) box {
+	r := func(v Vec3) Vec3 {
+		switch facing {
+		case "south":
+			return Vec3{1 - v.X, v.Y, 1 - v.Z}
+		case "east":
+			return Vec3{1 - v.Z, v.Y, v.X}
+		case "west":
+			return Vec3{v.Z, v.Y, 1 - v.X}
+		}
+		return v
+	}
+	p, q := r(b.Min), r(b.Max)
+	return box{
+		Vec3{minFloat(p.X, q.X), minFloat(p.Y, q.Y), minFloat(p.Z, q.Z)},
+		Vec3{maxFloat(p.X, q.X), maxFloat(p.Y, q.Y), maxFloat(p.Z, q.Z)},
+	}
+}
+
+// flip mirrors a box vertically, used for top slabs, stairs and trapdoors.
+func (b box) flip() box {
+	return box{Vec3{b.Min.X, 1 - b.Max.Y, b.Min.Z}, Vec3{b.Max.X, 1 - b.Min.Y, b.Max.Z}}
+}
+
+func blockName(b block.Block) string {
+	return strings.TrimPrefix(b.ID(), "minecraft:")
+}
+
+// invisibleBlocks have no model at all.
+var invisibleBlocks = map[string]bool{
+	"air": true, "cave_air": true, "void_air": true, "light": true,
+	"barrier": true, "structure_void": true, "moving_piston": true,
+}
+
+// transparentBlocks are full blocks that don't hide the faces of their neighbours.
+var transparentBlocks = map[string]bool{
+	"glass": true, "tinted_glass": true, "ice": true, "frosted_ice": true,
+	"slime_block": true, "honey_block": true, "spawner": true, "beacon": true,
+	"water": true, "lava": true, "bubble_column": true,
+}
+
+// plantBlocks have no collision box and are drawn as crossed planes in game.
+var plantBlocks = map[string]bool{
+	"grass": true, "short_grass": true, "tall_grass": true, "fern": true, "large_fern": true,
+	"dead_bush": true, "dandelion": true, "poppy": true, "blue_orchid": true, "allium": true,
+	"azure_bluet": true, "oxeye_daisy": true, "cornflower": true, "lily_of_the_valley": true,
+	"wither_rose": true, "torchflower": true, "sunflower": true, "lilac": true, "rose_bush": true,
+	"peony": true, "pitcher_plant": true, "wheat": true, "carrots": true, "potatoes": true,
+	"beetroots": true, "torchflower_crop": true, "pitcher_crop": true, "melon_stem": true,
+	"pumpkin_stem": true, "attached_melon_stem": true, "attached_pumpkin_stem": true,
+	"sugar_cane": true, "kelp": true, "kelp_plant": true, "seagrass": true, "tall_seagrass": true,
+	"sweet_berry_bush": true, "nether_sprouts": true, "crimson_roots": true, "warped_roots": true,
+	"crimson_fungus": true, "warped_fungus": true, "brown_mushroom": true, "red_mushroom": true,
+	"weeping_vines": true, "weeping_vines_plant": true, "twisting_vines": true,
+	"twisting_vines_plant": true, "cave_vines": true, "cave_vines_plant": true,
+	"hanging_roots": true, "spore_blossom": true, "cobweb": true, "nether_wart": true,
+	"bamboo_sapling": true, "fire": true, "soul_fire": true,
+}
+
+// blockBoxes approximates the model of b with a few boxes. Full blocks return
+// fullCube, blocks without a model return nil.
+func blockBoxes(b block.Block) []box {
+	name := blockName(b)
+	switch {
+	case invisibleBlocks[name]:
+		return nil
+	case strings.HasSuffix(name, "_leaves") || strings.HasSuffix(name, "stained_glass") || transparentBlocks[name]:
+		return fullCube
+	case plantBlocks[name] || strings.HasSuffix(name, "_sapling") || strings.HasSuffix(name, "_tulip") ||
+		strings.HasSuffix(name, "_coral") || strings.HasSuffix(name, "_coral_fan"):
+		return []box{pxBox(2, 0, 2, 14, 13, 14)}
+	case strings.HasSuffix(name, "_slab"):
+		switch property(b, "type") {
+		case "double":
+			return fullCube
+		case "top":
+			return []box{pxBox(0, 8, 0, 16, 16, 16)}
+		}
+		return []box{pxBox(0, 0, 0, 16, 8, 16)}
+	case strings.HasSuffix(name, "_stairs"):
+		boxes := []box{pxBox(0, 0, 0, 16, 8, 16), pxBox(0, 8, 0, 16, 16, 8).rotate(property(b, "facing"))}
+		if property(b, "half") == "top" {
+			boxes[0], boxes[1] = boxes[0].flip(), boxes[1].flip()
+		}
+		return boxes
+	case strings.HasSuffix(name, "_fence_gate"):
+		if property(b, "open") == "true" {
+			return []box{pxBox(0, 0, 7, 2, 16, 9).rotate(property(b, "facing")), pxBox(14, 0, 7, 16, 16, 9).rotate(property(b, "facing"))}
+		}
+		return []box{pxBox(0, 0, 7, 16, 16, 9).rotate(property(b, "facing"))}
+	case strings.HasSuffix(name, "_fence"):
+		return connectedBoxes(b, pxBox(6, 0, 6, 10, 16, 10), pxBox(7, 6, 0, 9, 15, 6))
+	case strings.HasSuffix(name, "_wall"):
+		return connectedBoxes(b, pxBox(4, 0, 4, 12, 16, 12), pxBox(5, 0, 0, 11, 14, 4))
+	case strings.HasSuffix(name, "_pane") || name == "iron_bars":
+		return connectedBoxes(b, pxBox(7, 0, 7, 9, 16, 9), pxBox(7, 0, 0, 9, 16, 7))
+	case strings.HasSuffix(name, "_carpet"):
+		return []box{pxBox(0, 0, 0, 16, 1, 16)}
+	case strings.HasSuffix(name, "_pressure_plate"):
+		return []box{pxBox(1, 0, 1, 15, 1, 15)}
+	case strings.HasSuffix(name, "_trapdoor"):
+		if property(b, "open") == "true" {
+			return []box{pxBox(0, 0, 13, 16, 16, 16).rotate(property(b, "facing"))}
+		}
+		if property(b, "half") == "top" {
+			return []box{pxBox(0, 13, 0, 16, 16, 16)}
+		}
+		return []box{pxBox(0, 0, 0, 16, 3, 16)}
+	case strings.HasSuffix(name, "_door"):
+		facing := property(b, "facing")
+		if property(b, "open") == "true" {
+			facing = rotateFacing(facing, property(b, "hinge") == "left")
+		}
+		return []box{pxBox(0, 0, 13, 16, 16, 16).rotate(facing)}
+	case strings.HasSuffix(name, "_bed"):
+		return []box{pxBox(0, 0, 0, 16, 9, 16)}
+	case strings.HasSuffix(name, "_button"):
+		return []box{pxBox(5, 6, 14, 11, 10, 16).rotate(property(b, "facing"))}
+	case strings.HasSuffix(name, "rail") || name == "redstone_wire" || name == "tripwire" || name == "lily_pad":
+		return []box{pxBox(0, 0, 0, 16, 1, 16)}
+	case name == "ladder" || name == "vine" || name == "glow_lichen" || name == "sculk_vein":
+		return []box{pxBox(0, 0, 15, 16, 16, 16).rotate(property(b, "facing"))}
+	case strings.HasSuffix(name, "torch") || strings.HasSuffix(name, "_rod") || name == "lever" || name == "tripwire_hook":
+		return []box{pxBox(7, 0, 7, 9, 10, 9)}
+	case strings.HasSuffix(name, "lantern") && name != "sea_lantern":
+		return []box{pxBox(5, 0, 5, 11, 9, 11)}
+	case strings.HasSuffix(name, "_sign") || strings.HasSuffix(name, "_banner") || strings.HasSuffix(name, "_head") ||
+		strings.HasSuffix(name, "_skull") || strings.HasSuffix(name, "flower_pot") || strings.HasPrefix(name, "potted_") ||
+		strings.HasSuffix(name, "candle") || strings.HasSuffix(name, "amethyst_bud") || name == "amethyst_cluster":
+		return []box{pxBox(4, 0, 4, 12, 8, 12)}
+	case name == "snow":
+		layers, _ := strconv.Atoi(property(b, "layers"))
+		return []box{pxBox(0, 0, 0, 16, float64(2*max(layers, 1)), 16)}
+	case strings.HasSuffix(name, "chest"):
+		return []box{pxBox(1, 0, 1, 15, 14, 15)}
+	case name == "farmland" || name == "dirt_path":
+		return []box{pxBox(0, 0, 0, 16, 15, 16)}
+	case name == "cactus":
+		return []box{pxBox(1, 0, 1, 15, 16, 15)}
+	case name == "enchanting_table":
+		return []box{pxBox(0, 0, 0, 16, 12, 16)}
+	case name == "daylight_detector" || strings.HasSuffix(name, "sculk_sensor"):
+		return []box{pxBox(0, 0, 0, 16, 6, 16)}
+	case name == "repeater" || name == "comparator":
+		return []box{pxBox(0, 0, 0, 16, 2, 16)}
+	case name == "cake":
+		return []box{pxBox(1, 0, 1, 15, 8, 15)}
+	case name == "chain":
+		return []box{pxBox(6.5, 0, 6.5, 9.5, 16, 9.5)}
+	}
+	return fullCube
+}
+
+// connectedBoxes builds fences, walls and panes from a centre post and an arm
+// pointing north, which is rotated for every connected side.
+func connectedBoxes(b block.Block, post, arm box) []box {
+	boxes := []box{post}
+	for _, side := range []string{"north", "east", "south", "west"} {
+		switch property(b, side) {
+		case "true", "low":
+			boxes = append(boxes, arm.rotate(side))
+		case "tall":
+			tall := arm
+			tall.Max.Y = 1
+			boxes = append(boxes, tall.rotate(side))
+		}
+	}
+	return boxes
+}
+
+func rotateFacing(facing string, clockwise bool) string {
+	order := []string{"north", "east", "south", "west"}
+	for i, f := range order {
+		if f == facing {
+			if clockwise {
+				return order[(i+1)%4]
+			}
+			return order[(i+3)%4]
+		}
+	}
+	return facing
+}
+
+// isFullBlock reports whether b fills the whole block space.
+func isFullBlock(b block.Block) bool {
+	boxes := blockBoxes(b)
+	return len(boxes) == 1 && boxes[0] == fullCube[0]
+}
+
+// isTransparent reports whether b lets the faces of its neighbours show through.
+func isTransparent(b block.Block) bool {
+	name := blockName(b)
+	return transparentBlocks[name] || strings.HasSuffix(name, "_leaves") || strings.HasSuffix(name, "stained_glass") || !isFullBlock(b)
+}
+
+// isOpaqueFullBlock reports whether b hides every face next to it.
+func isOpaqueFullBlock(b block.Block) bool {
+	return isFullBlock(b) && !isTransparent(b)
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
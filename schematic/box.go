@@ -0,0 +1,46 @@
+package schematic
+
+// Box is an axis aligned box of blocks, Min and Max are both included.
+type Box struct {
+	Min, Max Vec3D
+}
+
+// NewBox returns the box between two corners given in any order.
+func NewBox(x0, y0, z0, x1, y1, z1 int) Box {
+	return Box{
+		Min: Vec3D{int32(minInt(x0, x1)), int32(minInt(y0, y1)), int32(minInt(z0, z1))},
+		Max: Vec3D{int32(max(x0, x1)), int32(max(y0, y1)), int32(max(z0, z1))},
+	}
+}
+
+func (b Box) Contains(x, y, z int) bool {
+	return x >= int(b.Min.X) && y >= int(b.Min.Y) && z >= int(b.Min.Z) &&
+		x <= int(b.Max.X) && y <= int(b.Max.Y) && z <= int(b.Max.Z)
+}
+
+// Empty reports whether the box holds no blocks.
+func (b Box) Empty() bool {
+	return b.Min.X > b.Max.X || b.Min.Y > b.Max.Y || b.Min.Z > b.Max.Z
+}
+
+// Volume returns the number of blocks in the box.
+func (b Box) Volume() int {
+	if b.Empty() {
+		return 0
+	}
+	return int(b.Max.X-b.Min.X+1) * int(b.Max.Y-b.Min.Y+1) * int(b.Max.Z-b.Min.Z+1)
+}
+
+// Intersect returns the part of b inside o, which may be empty.
+func (b Box) Intersect(o Box) Box {
+	return Box{
+		Min: Vec3D{max32(b.Min.X, o.Min.X), max32(b.Min.Y, o.Min.Y), max32(b.Min.Z, o.Min.Z)},
+		Max: Vec3D{min32(b.Max.X, o.Max.X), min32(b.Max.Y, o.Max.Y), min32(b.Max.Z, o.Max.Z)},
+	}
+}
+
+// Bounds returns the box of the whole project.
+func (p *Project) Bounds() Box {
+	s := p.Size()
+	return Box{Max: Vec3D{s.X - 1, s.Y - 1, s.Z - 1}}
+}
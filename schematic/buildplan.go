@@ -0,0 +1,278 @@
+package schematic
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Tnze/go-mc/nbt"
+	"io"
+	"sort"
+)
+
+// BuildPlan is an order to place the blocks of a project in survival.
+type BuildPlan struct {
+	//Size of the planned project
+	Size  Vec3D
+	Steps []BuildStep
+
+	dataVersion int32
+}
+
+// BuildStep is a group of blocks placed one after another.
+type BuildStep struct {
+	//Name is "layer <y>" or "fluids"
+	Name   string
+	Blocks []Placement
+}
+
+// Placement is a block of a BuildStep.
+type Placement struct {
+	Pos   Vec3D
+	State BlockState
+
+	//Support is the block this one is attached to or stands on, placed
+	//before it
+	Support *Vec3D
+
+	//Unsupported is set when the support isn't in the project, the block
+	//would break or fall
+	Unsupported bool
+
+	//Scaffold is set when no neighbour is placed yet, the block needs
+	//something temporary to be placed against
+	Scaffold bool
+
+	nbt *nbt.RawMessage
+}
+
+// PlanBuild orders the blocks of the project for building in survival. The
+// blocks go layer by layer from the bottom, each layer growing outwards from
+// the blocks next to what is already built. Blocks that need another block,
+// like torches, buttons, plants, doors and sand, come after it, in the layer
+// of the block they hang from if that is higher. Water and lava come last.
+func (p *Project) PlanBuild() *BuildPlan {
+	plan := &BuildPlan{Size: p.Size(), dataVersion: p.MinecraftDataVersion}
+	states := make(map[Vec3D]BlockState)
+	var fluids []Vec3D
+	p.each(p.Bounds(), true, func(x, y, z int, s BlockState) bool {
+		v := Vec3D{int32(x), int32(y), int32(z)}
+		states[v] = s
+		if isFluid(shortName(s)) {
+			fluids = append(fluids, v)
+		}
+		return true
+	})
+	p.mu.RLock()
+	entities := make(map[Vec3D]nbt.RawMessage, len(p.blockEntities))
+	for v, m := range p.blockEntities {
+		entities[v] = m
+	}
+	p.mu.RUnlock()
+
+	supports := make(map[Vec3D]Vec3D)
+	for v, s := range states {
+		if o, ok := supportOffset(s); ok {
+			supports[v] = Vec3D{v.X + o.X, v.Y + o.Y, v.Z + o.Z}
+		}
+	}
+	// a dependent block is placed in the layer of its support, and after it
+	layers := make(map[Vec3D]int32)
+	depths := make(map[Vec3D]int)
+	var layer func(v Vec3D, n int) (int32, int)
+	layer = func(v Vec3D, n int) (int32, int) {
+		if l, ok := layers[v]; ok {
+			return l, depths[v]
+		}
+		l, d := v.Y, 0
+		if s, ok := supports[v]; ok && n < 64 {
+			if _, exists := states[s]; exists && !isFluid(shortName(states[s])) {
+				sl, sd := layer(s, n+1)
+				l, d = max32(l, sl), sd+1
+			}
+		}
+		layers[v], depths[v] = l, d
+		return l, d
+	}
+
+	// blocks on a fluid, like lily pads, come after it
+	byLayer := make(map[int32][]Vec3D)
+	var onFluids []Vec3D
+	for v, s := range states {
+		if isFluid(shortName(s)) {
+			continue
+		}
+		if sup, ok := supports[v]; ok && isFluid(shortName(states[sup])) {
+			onFluids = append(onFluids, v)
+			continue
+		}
+		l, _ := layer(v, 0)
+		byLayer[l] = append(byLayer[l], v)
+	}
+
+	placed := make(map[Vec3D]bool)
+	place := func(step *BuildStep, v Vec3D, scaffold bool) {
+		pl := Placement{Pos: v, State: states[v], Scaffold: scaffold}
+		if s, ok := supports[v]; ok {
+			pl.Support = &s
+			// the ground holds blocks on the bottom layer
+			pl.Unsupported = !placed[s] && s.Y >= 0
+			pl.Scaffold = false
+		}
+		if m, ok := entities[v]; ok {
+			pl.nbt = &m
+		}
+		placed[v] = true
+		step.Blocks = append(step.Blocks, pl)
+	}
+	hasNeighbour := func(v Vec3D) bool {
+		if v.Y == 0 {
+			return true
+		}
+		for _, d := range neighbours {
+			if placed[Vec3D{v.X + d.X, v.Y + d.Y, v.Z + d.Z}] {
+				return true
+			}
+		}
+		return false
+	}
+
+	for y := int32(0); y < plan.Size.Y; y++ {
+		blocks := byLayer[y]
+		if len(blocks) == 0 {
+			continue
+		}
+		sort.Slice(blocks, func(i, j int) bool { return less(blocks[i], blocks[j]) })
+		step := BuildStep{Name: fmt.Sprintf("layer %d", y)}
+
+		// the blocks of the layer grow from those next to built ones, a block
+		// without any gets a scaffold and the layer grows on from it
+		regular := make(map[Vec3D]bool)
+		var queue, dependent []Vec3D
+		for _, v := range blocks {
+			if _, ok := supports[v]; ok {
+				dependent = append(dependent, v)
+				continue
+			}
+			regular[v] = true
+			if hasNeighbour(v) {
+				queue = append(queue, v)
+			}
+		}
+		// the next island starts at the first unplaced block, those before next
+		// are all placed
+		next := 0
+		for len(regular) > 0 {
+			scaffold := false
+			if len(queue) == 0 {
+				for !regular[blocks[next]] {
+					next++
+				}
+				queue, scaffold = append(queue, blocks[next]), true
+			}
+			for len(queue) > 0 {
+				v := queue[0]
+				queue = queue[1:]
+				if !regular[v] {
+					continue
+				}
+				delete(regular, v)
+				place(&step, v, scaffold && !hasNeighbour(v))
+				scaffold = false
+				for _, d := range neighbours {
+					if d.Y == 0 {
+						queue = append(queue, Vec3D{v.X + d.X, v.Y, v.Z + d.Z})
+					}
+				}
+			}
+		}
+
+		sort.SliceStable(dependent, func(i, j int) bool { return depths[dependent[i]] < depths[dependent[j]] })
+		for _, v := range dependent {
+			place(&step, v, false)
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+
+	if len(fluids) > 0 {
+		sort.Slice(fluids, func(i, j int) bool { return less(fluids[i], fluids[j]) })
+		sort.Slice(onFluids, func(i, j int) bool { return less(onFluids[i], onFluids[j]) })
+		step := BuildStep{Name: "fluids"}
+		for _, v := range append(fluids, onFluids...) {
+			place(&step, v, false)
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+	return plan
+}
+
+// neighbours are the offsets of the six blocks sharing a face.
+var neighbours = []Vec3D{{0, -1, 0}, {0, 1, 0}, {0, 0, -1}, {0, 0, 1}, {-1, 0, 0}, {1, 0, 0}}
+
+// less orders positions like the blocks are stored, by y, z then x.
+func less(a, b Vec3D) bool {
+	if a.Y != b.Y {
+		return a.Y < b.Y
+	}
+	if a.Z != b.Z {
+		return a.Z < b.Z
+	}
+	return a.X < b.X
+}
+
+// Projects returns a project of the plan's size for every step, holding the
+// blocks placed in it.
+func (bp *BuildPlan) Projects(name string) []*Project {
+	var projects []*Project
+	for i, step := range bp.Steps {
+		p := NewProject(fmt.Sprintf("%s_%d", name, i), int(bp.Size.X), int(bp.Size.Y), int(bp.Size.Z), WithDataVersion(int(bp.dataVersion)))
+		for _, b := range step.Blocks {
+			p.SetBlock(int(b.Pos.X), int(b.Pos.Y), int(b.Pos.Z), b.State.Properties)
+			if b.nbt != nil {
+				_ = p.SetBlockEntity(int(b.Pos.X), int(b.Pos.Y), int(b.Pos.Z), *b.nbt)
+			}
+		}
+		projects = append(projects, p)
+	}
+	return projects
+}
+
+// WriteJSON writes the plan as a JSON list of steps, each block with its
+// position, block state in command syntax and the flags of Placement.
+func (bp *BuildPlan) WriteJSON(w io.Writer) error {
+	type jsonBlock struct {
+		Pos         [3]int32  `json:"pos"`
+		Block       string    `json:"block"`
+		NBT         string    `json:"nbt,omitempty"`
+		Support     *[3]int32 `json:"support,omitempty"`
+		Unsupported bool      `json:"unsupported,omitempty"`
+		Scaffold    bool      `json:"scaffold,omitempty"`
+	}
+	type jsonStep struct {
+		Name   string      `json:"name"`
+		Blocks []jsonBlock `json:"blocks"`
+	}
+	steps := make([]jsonStep, len(bp.Steps))
+	for i, step := range bp.Steps {
+		steps[i] = jsonStep{Name: step.Name, Blocks: make([]jsonBlock, len(step.Blocks))}
+		for j, b := range step.Blocks {
+			jb := jsonBlock{
+				Pos:         [3]int32{b.Pos.X, b.Pos.Y, b.Pos.Z},
+				Block:       BlockString(b.State),
+				Unsupported: b.Unsupported,
+				Scaffold:    b.Scaffold,
+			}
+			if b.nbt != nil {
+				jb.NBT = b.nbt.String()
+			}
+			if b.Support != nil {
+				jb.Support = &[3]int32{b.Support.X, b.Support.Y, b.Support.Z}
+			}
+			steps[i].Blocks[j] = jb
+		}
+	}
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(struct {
+		Size  [3]int32   `json:"size"`
+		Steps []jsonStep `json:"steps"`
+	}{[3]int32{bp.Size.X, bp.Size.Y, bp.Size.Z}, steps})
+}
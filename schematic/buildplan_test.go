@@ -0,0 +1,101 @@
+package schematic
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/Tnze/go-mc/level/block"
+	"testing"
+)
+
+func TestPlanBuild(t *testing.T) {
+	p := NewProject("plan", 5, 5, 5)
+	p.Fill(NewBox(0, 0, 0, 4, 0, 4), Single(block.Stone{}), nil)
+	p.Fill(NewBox(2, 1, 2, 2, 2, 2), Single(block.Stone{}), nil)
+	p.Fill(NewBox(2, 2, 0, 2, 2, 1), Single(block.Stone{}), nil)
+	p.SetBlock(3, 2, 2, block.WallTorch{Facing: block.East})
+	p.SetBlock(2, 1, 0, block.Lantern{Hanging: true})
+	p.SetBlock(0, 1, 0, block.Sand{})
+	p.SetBlock(0, 4, 4, block.Stone{})
+	p.SetBlock(4, 1, 4, block.Water{})
+	p.SetBlock(4, 2, 4, block.LilyPad{})
+	p.SetBlock(4, 1, 0, block.Torch{})
+	p.SetBlock(1, 3, 4, block.Torch{})
+
+	plan := p.PlanBuild()
+	var names []string
+	found := make(map[Vec3D]Placement)
+	order := make(map[Vec3D]int)
+	total := 0
+	for _, step := range plan.Steps {
+		names = append(names, step.Name)
+		for _, b := range step.Blocks {
+			found[b.Pos] = b
+			order[b.Pos] = total
+			total++
+		}
+	}
+	want := []string{"layer 0", "layer 1", "layer 2", "layer 3", "layer 4", "fluids"}
+	if len(names) != len(want) {
+		t.Fatalf("Error, steps %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("Error, steps %v, want %v", names, want)
+		}
+	}
+	if int32(total) != p.MetaData.TotalBlocks {
+		t.Fatalf("Error, %d blocks planned, want %d", total, p.MetaData.TotalBlocks)
+	}
+
+	before := func(a, b Vec3D) {
+		if order[a] >= order[b] {
+			t.Fatalf("Error, %v is placed after %v", a, b)
+		}
+	}
+	before(Vec3D{2, 2, 0}, Vec3D{2, 1, 0})
+	before(Vec3D{2, 2, 2}, Vec3D{3, 2, 2})
+	before(Vec3D{0, 0, 0}, Vec3D{0, 1, 0})
+	before(Vec3D{4, 1, 4}, Vec3D{4, 2, 4})
+	if s := found[Vec3D{0, 1, 0}].Support; s == nil || *s != (Vec3D{0, 0, 0}) {
+		t.Fatalf("Error, sand support %v", s)
+	}
+	if !found[Vec3D{0, 4, 4}].Scaffold || found[Vec3D{2, 1, 2}].Scaffold {
+		t.Fatalf("Error, scaffold flags %+v %+v", found[Vec3D{0, 4, 4}], found[Vec3D{2, 1, 2}])
+	}
+	if !found[Vec3D{1, 3, 4}].Unsupported || found[Vec3D{4, 1, 0}].Unsupported || found[Vec3D{2, 1, 0}].Unsupported {
+		t.Fatalf("Error, unsupported flags")
+	}
+
+	projects := plan.Projects("plan")
+	if len(projects) != len(plan.Steps) {
+		t.Fatalf("Error, %d projects for %d steps", len(projects), len(plan.Steps))
+	}
+	if projects[5].GetBlock(4, 2, 4).Name != "minecraft:lily_pad" || projects[5].MetaData.TotalBlocks != 2 {
+		t.Fatalf("Error, fluids project")
+	}
+
+	var buf bytes.Buffer
+	if err := plan.WriteJSON(&buf); err != nil {
+		t.Fatalf("Error, %v", err)
+	}
+	var decoded struct {
+		Steps []struct {
+			Name   string
+			Blocks []struct {
+				Pos     [3]int32
+				Block   string
+				Support *[3]int32
+			}
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded.Steps) != 6 {
+		t.Fatalf("Error, JSON %v: %s", err, buf.Bytes())
+	}
+	lantern := false
+	for _, b := range decoded.Steps[2].Blocks {
+		lantern = lantern || b.Block == "minecraft:lantern[hanging=true,waterlogged=false]" && b.Support != nil && *b.Support == [3]int32{2, 2, 0}
+	}
+	if !lantern {
+		t.Fatalf("Error, no lantern in layer 2 %+v", decoded.Steps[2])
+	}
+}
@@ -0,0 +1,209 @@
+package schematic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity tells how bad an Issue is.
+type Severity int
+
+const (
+	//SeverityInfo may be intended, like a block attached to one outside the
+	//project
+	SeverityInfo Severity = iota
+
+	//SeverityWarning will look wrong or change in game
+	SeverityWarning
+
+	//SeverityError can't be built in survival or breaks right away
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	}
+	return "error"
+}
+
+// IssueKind is the rule an Issue breaks.
+type IssueKind string
+
+const (
+	//Unobtainable blocks have no item in survival
+	Unobtainable IssueKind = "unobtainable"
+
+	//Unsupported blocks break or fall where they are
+	Unsupported IssueKind = "unsupported"
+
+	//Inconsistent states don't fit their neighbours or each other
+	Inconsistent IssueKind = "inconsistent"
+)
+
+// Issue is a problem with a block found by Check.
+type Issue struct {
+	Pos      Vec3D
+	State    BlockState
+	Kind     IssueKind
+	Severity Severity
+	Message  string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%d %d %d %s %s: %s", i.Pos.X, i.Pos.Y, i.Pos.Z, i.Severity, i.Kind, i.Message)
+}
+
+// unobtainableBlocks have no item a survival player can get.
+var unobtainableBlocks = map[string]bool{
+	"bedrock": true, "barrier": true, "light": true, "command_block": true,
+	"chain_command_block": true, "repeating_command_block": true, "structure_block": true,
+	"structure_void": true, "jigsaw": true, "spawner": true, "trial_spawner": true,
+	"budding_amethyst": true, "petrified_oak_slab": true, "end_portal": true,
+	"end_portal_frame": true, "end_gateway": true, "reinforced_deepslate": true,
+	"moving_piston": true, "piston_head": true, "frogspawn": true,
+}
+
+// soils are the blocks plants in general grow on.
+var soils = map[string]bool{
+	"dirt": true, "grass_block": true, "podzol": true, "coarse_dirt": true, "rooted_dirt": true,
+	"mycelium": true, "moss_block": true, "farmland": true, "mud": true, "muddy_mangrove_roots": true,
+}
+
+// plantSoil returns the blocks the plant grows on, nil if any block holding
+// it will do.
+func plantSoil(name string) map[string]bool {
+	switch name {
+	case "wheat", "carrots", "potatoes", "beetroots", "torchflower_crop", "pitcher_crop",
+		"melon_stem", "pumpkin_stem", "attached_melon_stem", "attached_pumpkin_stem":
+		return map[string]bool{"farmland": true}
+	case "nether_wart":
+		return map[string]bool{"soul_sand": true}
+	case "cactus":
+		return map[string]bool{"sand": true, "red_sand": true, "cactus": true}
+	case "sugar_cane":
+		return map[string]bool{"sugar_cane": true, "dirt": true, "grass_block": true, "sand": true, "red_sand": true,
+			"podzol": true, "coarse_dirt": true, "rooted_dirt": true, "mycelium": true, "moss_block": true, "mud": true}
+	case "dead_bush":
+		return map[string]bool{"sand": true, "red_sand": true, "terracotta": true, "dirt": true, "podzol": true, "coarse_dirt": true}
+	case "crimson_fungus", "warped_fungus", "crimson_roots", "warped_roots", "nether_sprouts":
+		return map[string]bool{"crimson_nylium": true, "warped_nylium": true, "soul_soil": true, "dirt": true, "grass_block": true, "mycelium": true}
+	case "brown_mushroom", "red_mushroom", "kelp", "kelp_plant", "seagrass", "tall_seagrass", "fire", "soul_fire",
+		"bamboo_sapling", "twisting_vines", "twisting_vines_plant", "cobweb":
+		return nil
+	}
+	if strings.HasSuffix(name, "_coral") || strings.HasSuffix(name, "_coral_fan") {
+		return nil
+	}
+	return soils
+}
+
+// Check scans the project for blocks that can't be obtained in survival,
+// blocks that can't stay where they are and inconsistent states. Blocks
+// attached to a position outside the project are reported as info, except
+// below the bottom layer, which is taken to stand on the ground.
+func (p *Project) Check() []Issue {
+	var issues []Issue
+	add := func(v Vec3D, s BlockState, kind IssueKind, sev Severity, format string, a ...any) {
+		issues = append(issues, Issue{v, s, kind, sev, fmt.Sprintf(format, a...)})
+	}
+	size := p.Size()
+	at := func(v Vec3D) BlockState { return p.GetBlock(int(v.X), int(v.Y), int(v.Z)) }
+	offset := func(v, o Vec3D) Vec3D { return Vec3D{v.X + o.X, v.Y + o.Y, v.Z + o.Z} }
+
+	p.ForEachNonAir(func(x, y, z int, s BlockState) {
+		v := Vec3D{int32(x), int32(y), int32(z)}
+		name := shortName(s)
+		if isAirName(name) {
+			return
+		}
+		if unobtainableBlocks[name] || strings.HasPrefix(name, "infested_") {
+			add(v, s, Unobtainable, SeverityError, "%s can't be obtained in survival", name)
+		}
+
+		if o, ok := supportOffset(s); ok {
+			sv := offset(v, o)
+			support := at(sv)
+			supportName := shortName(support)
+			switch {
+			case sv.Y < 0:
+			case size.outOfRange(int(sv.X), int(sv.Y), int(sv.Z)):
+				add(v, s, Unsupported, SeverityInfo, "%s rests on %d %d %d outside the project", name, sv.X, sv.Y, sv.Z)
+			case isGravityBlock(name) && (isAirName(supportName) || isFluid(supportName)):
+				add(v, s, Unsupported, SeverityWarning, "%s falls, there is %s below", name, supportName)
+			case isGravityBlock(name):
+			case isAirName(supportName):
+				add(v, s, Unsupported, SeverityError, "%s breaks, the block it needs at %d %d %d is air", name, sv.X, sv.Y, sv.Z)
+			case isPlant(name) && o.Y < 0 && property(s.Properties, "half") != "upper":
+				if soil := plantSoil(name); soil != nil && !soil[supportName] {
+					add(v, s, Unsupported, SeverityError, "%s can't grow on %s", name, supportName)
+				}
+			}
+		}
+
+		switch {
+		case strings.HasSuffix(name, "_door") || property(s.Properties, "half") == "upper" || property(s.Properties, "half") == "lower":
+			half := property(s.Properties, "half")
+			if half != "upper" && half != "lower" {
+				break
+			}
+			o := Vec3D{0, 1, 0}
+			other := "upper"
+			if half == "upper" {
+				o, other = Vec3D{0, -1, 0}, "lower"
+			}
+			ov := offset(v, o)
+			if size.outOfRange(int(ov.X), int(ov.Y), int(ov.Z)) {
+				break
+			}
+			n := at(ov)
+			if n.Name != s.Name || property(n.Properties, "half") != other {
+				add(v, s, Unsupported, SeverityError, "%s is missing its %s half", name, other)
+			} else if strings.HasSuffix(name, "_door") && (property(n.Properties, "facing") != property(s.Properties, "facing") ||
+				property(n.Properties, "hinge") != property(s.Properties, "hinge") || property(n.Properties, "open") != property(s.Properties, "open")) {
+				add(v, s, Inconsistent, SeverityWarning, "the halves of %s differ in facing, hinge or open", name)
+			}
+		case strings.HasSuffix(name, "_bed"):
+			f := facingOffset(property(s.Properties, "facing"))
+			o, other := f, "head"
+			if property(s.Properties, "part") == "head" {
+				o, other = Vec3D{-f.X, -f.Y, -f.Z}, "foot"
+			}
+			ov := offset(v, o)
+			if size.outOfRange(int(ov.X), int(ov.Y), int(ov.Z)) {
+				break
+			}
+			n := at(ov)
+			if n.Name != s.Name || property(n.Properties, "part") != other || property(n.Properties, "facing") != property(s.Properties, "facing") {
+				add(v, s, Unsupported, SeverityError, "%s is missing its %s", name, other)
+			}
+		case name == "chest" || name == "trapped_chest":
+			t := property(s.Properties, "type")
+			if t == "single" {
+				break
+			}
+			facing := property(s.Properties, "facing")
+			ov := offset(v, facingOffset(rotateFacing(facing, t == "left")))
+			n := at(ov)
+			want := map[string]string{"left": "right", "right": "left"}[t]
+			if !size.outOfRange(int(ov.X), int(ov.Y), int(ov.Z)) && (n.Name != s.Name || property(n.Properties, "type") != want || property(n.Properties, "facing") != facing) {
+				add(v, s, Inconsistent, SeverityWarning, "%s is the %s half of a double chest without its other half", name, t)
+			}
+		case strings.HasSuffix(name, "_slab"):
+			if property(s.Properties, "type") == "double" && property(s.Properties, "waterlogged") == "true" {
+				add(v, s, Inconsistent, SeverityWarning, "double %s can't be waterlogged", name)
+			}
+		case strings.HasSuffix(name, "_leaves"):
+			if property(s.Properties, "persistent") == "false" && property(s.Properties, "distance") == "7" {
+				add(v, s, Inconsistent, SeverityWarning, "%s will decay, it isn't persistent and is far from logs", name)
+			}
+		}
+	})
+
+	sort.SliceStable(issues, func(i, j int) bool { return less(issues[i].Pos, issues[j].Pos) })
+	return issues
+}
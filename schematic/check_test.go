@@ -0,0 +1,62 @@
+package schematic
+
+import (
+	"github.com/Tnze/go-mc/level/block"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	with := func(b block.Block, props map[string]string) block.Block { return withProperties(b, props) }
+	p := NewProject("check", 8, 4, 8)
+	p.Fill(NewBox(0, 0, 0, 7, 0, 7), Single(block.Stone{}), nil)
+	p.SetBlock(0, 0, 0, block.Bedrock{})
+	p.SetBlock(1, 1, 1, block.Torch{}) // fine
+	p.SetBlock(1, 3, 1, block.Torch{}) // on air
+	p.SetBlock(2, 2, 2, block.Sand{})  // floating
+	p.SetBlock(3, 1, 3, block.Poppy{}) // on stone
+	p.SetBlock(4, 0, 4, block.GrassBlock{})
+	p.SetBlock(4, 1, 4, block.Poppy{}) // on grass
+	p.SetBlock(5, 1, 5, with(block.OakDoor{}, map[string]string{"half": "lower", "facing": "north"}))
+	p.SetBlock(6, 1, 1, with(block.RedBed{}, map[string]string{"part": "foot", "facing": "south"}))
+	p.SetBlock(6, 1, 2, with(block.RedBed{}, map[string]string{"part": "head", "facing": "south"}))
+	p.SetBlock(2, 1, 6, with(block.RedBed{}, map[string]string{"part": "foot", "facing": "east"}))
+	p.SetBlock(0, 1, 6, with(block.OakSlab{}, map[string]string{"type": "double", "waterlogged": "true"}))
+	p.SetBlock(5, 1, 7, with(block.Chest{}, map[string]string{"type": "left", "facing": "north"}))
+	p.SetBlock(7, 3, 0, block.Lantern{Hanging: true}) // hangs from outside
+	p.SetBlock(7, 0, 7, block.Sand{})                 // on the ground below
+
+	issues := p.Check()
+	want := []struct {
+		pos  Vec3D
+		kind IssueKind
+		sev  Severity
+	}{
+		{Vec3D{0, 0, 0}, Unobtainable, SeverityError},
+		{Vec3D{1, 3, 1}, Unsupported, SeverityError},
+		{Vec3D{5, 1, 5}, Unsupported, SeverityError},
+		{Vec3D{3, 1, 3}, Unsupported, SeverityError},
+		{Vec3D{0, 1, 6}, Inconsistent, SeverityWarning},
+		{Vec3D{2, 1, 6}, Unsupported, SeverityError},
+		{Vec3D{5, 1, 7}, Inconsistent, SeverityWarning},
+		{Vec3D{2, 2, 2}, Unsupported, SeverityWarning},
+		{Vec3D{7, 3, 0}, Unsupported, SeverityInfo},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("Error, %d issues, want %d: %v", len(issues), len(want), issues)
+	}
+	found := make(map[Vec3D]Issue)
+	for _, i := range issues {
+		found[i.Pos] = i
+	}
+	for _, w := range want {
+		i, ok := found[w.pos]
+		if !ok || i.Kind != w.kind || i.Severity != w.sev {
+			t.Fatalf("Error, issue at %v: %v, want %s %s", w.pos, i, w.sev, w.kind)
+		}
+	}
+	for j := 1; j < len(issues); j++ {
+		if less(issues[j].Pos, issues[j-1].Pos) {
+			t.Fatalf("Error, issues out of order: %v", issues)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package schematic
+
+import (
+	"github.com/Tnze/go-mc/level/block"
+	"image/color"
+)
+
+// BlockColor pairs a block with the approximate average colour of its texture.
+type BlockColor struct {
+	Block block.Block
+	Color color.RGBA
+}
+
+// ColorTable is a list of blocks used for colour matching.
+type ColorTable []BlockColor
+
+// DefaultColorTable contains full, opaque blocks that are easy to obtain in survival.
+var DefaultColorTable = ColorTable{
+	{block.WhiteConcrete{}, rgb(207, 213, 214)},
+	{block.OrangeConcrete{}, rgb(224, 97, 0)},
+	{block.MagentaConcrete{}, rgb(169, 48, 159)},
+	{block.LightBlueConcrete{}, rgb(35, 137, 198)},
+	{block.YellowConcrete{}, rgb(241, 175, 21)},
+	{block.LimeConcrete{}, rgb(94, 168, 24)},
+	{block.PinkConcrete{}, rgb(213, 101, 142)},
+	{block.GrayConcrete{}, rgb(54, 57, 61)},
+	{block.LightGrayConcrete{}, rgb(125, 125, 115)},
+	{block.CyanConcrete{}, rgb(21, 119, 136)},
+	{block.PurpleConcrete{}, rgb(100, 31, 156)},
+	{block.BlueConcrete{}, rgb(44, 46, 143)},
+	{block.BrownConcrete{}, rgb(96, 59, 31)},
+	{block.GreenConcrete{}, rgb(73, 91, 36)},
+	{block.RedConcrete{}, rgb(142, 32, 32)},
+	{block.BlackConcrete{}, rgb(8, 10, 15)},
+
+	{block.WhiteWool{}, rgb(233, 236, 236)},
+	{block.OrangeWool{}, rgb(240, 118, 19)},
+	{block.MagentaWool{}, rgb(189, 68, 179)},
+	{block.LightBlueWool{}, rgb(58, 175, 217)},
+	{block.YellowWool{}, rgb(248, 197, 39)},
+	{block.LimeWool{}, rgb(112, 185, 25)},
+	{block.PinkWool{}, rgb(237, 141, 172)},
+	{block.GrayWool{}, rgb(62, 68, 71)},
+	{block.LightGrayWool{}, rgb(142, 142, 134)},
+	{block.CyanWool{}, rgb(21, 137, 145)},
+	{block.PurpleWool{}, rgb(121, 42, 172)},
+	{block.BlueWool{}, rgb(53, 57, 157)},
+	{block.BrownWool{}, rgb(114, 71, 40)},
+	{block.GreenWool{}, rgb(84, 109, 27)},
+	{block.RedWool{}, rgb(160, 39, 34)},
+	{block.BlackWool{}, rgb(20, 21, 25)},
+
+	{block.Terracotta{}, rgb(152, 94, 67)},
+	{block.WhiteTerracotta{}, rgb(209, 178, 161)},
+	{block.OrangeTerracotta{}, rgb(161, 83, 37)},
+	{block.MagentaTerracotta{}, rgb(149, 88, 108)},
+	{block.LightBlueTerracotta{}, rgb(113, 108, 137)},
+	{block.YellowTerracotta{}, rgb(186, 133, 35)},
+	{block.LimeTerracotta{}, rgb(103, 117, 52)},
+	{block.PinkTerracotta{}, rgb(161, 78, 78)},
+	{block.GrayTerracotta{}, rgb(57, 42, 35)},
+	{block.LightGrayTerracotta{}, rgb(135, 106, 97)},
+	{block.CyanTerracotta{}, rgb(86, 91, 91)},
+	{block.PurpleTerracotta{}, rgb(118, 70, 86)},
+	{block.BlueTerracotta{}, rgb(74, 59, 91)},
+	{block.BrownTerracotta{}, rgb(77, 51, 35)},
+	{block.GreenTerracotta{}, rgb(76, 83, 42)},
+	{block.RedTerracotta{}, rgb(143, 61, 46)},
+	{block.BlackTerracotta{}, rgb(37, 22, 16)},
+
+	{block.Stone{}, rgb(125, 125, 125)},
+	{block.SmoothStone{}, rgb(158, 158, 158)},
+	{block.Andesite{}, rgb(136, 136, 136)},
+	{block.Diorite{}, rgb(188, 188, 188)},
+	{block.Granite{}, rgb(149, 103, 85)},
+	{block.CobbledDeepslate{}, rgb(77, 77, 80)},
+	{block.Blackstone{}, rgb(42, 35, 40)},
+	{block.Calcite{}, rgb(223, 224, 220)},
+	{block.Tuff{}, rgb(108, 109, 102)},
+	{block.StoneBricks{}, rgb(122, 121, 122)},
+	{block.Bricks{}, rgb(150, 97, 83)},
+	{block.MudBricks{}, rgb(137, 103, 79)},
+	{block.NetherBricks{}, rgb(44, 21, 26)},
+	{block.Netherrack{}, rgb(97, 38, 38)},
+	{block.EndStone{}, rgb(219, 222, 158)},
+	{block.PurpurBlock{}, rgb(169, 125, 169)},
+	{block.Prismarine{}, rgb(99, 156, 151)},
+	{block.DarkPrismarine{}, rgb(51, 91, 75)},
+	{block.QuartzBlock{}, rgb(235, 229, 222)},
+	{block.BoneBlock{}, rgb(229, 225, 207)},
+	{block.Obsidian{}, rgb(15, 10, 24)},
+
+	{block.OakPlanks{}, rgb(162, 130, 78)},
+	{block.SprucePlanks{}, rgb(114, 84, 48)},
+	{block.BirchPlanks{}, rgb(192, 175, 121)},
+	{block.JunglePlanks{}, rgb(160, 115, 80)},
+	{block.AcaciaPlanks{}, rgb(168, 90, 50)},
+	{block.DarkOakPlanks{}, rgb(66, 43, 20)},
+	{block.CherryPlanks{}, rgb(226, 178, 172)},
+	{block.CrimsonPlanks{}, rgb(101, 48, 70)},
+	{block.WarpedPlanks{}, rgb(43, 104, 99)},
+
+	{block.Sandstone{}, rgb(216, 203, 155)},
+	{block.RedSandstone{}, rgb(181, 97, 31)},
+	{block.Dirt{}, rgb(134, 96, 67)},
+	{block.Clay{}, rgb(160, 166, 179)},
+	{block.PackedIce{}, rgb(141, 180, 250)},
+	{block.SnowBlock{}, rgb(249, 254, 254)},
+	{block.HayBlock{}, rgb(166, 139, 12)},
+
+	{block.GoldBlock{}, rgb(246, 208, 61)},
+	{block.IronBlock{}, rgb(220, 220, 220)},
+	{block.DiamondBlock{}, rgb(98, 237, 228)},
+	{block.EmeraldBlock{}, rgb(42, 203, 87)},
+	{block.LapisBlock{}, rgb(30, 67, 140)},
+	{block.RedstoneBlock{}, rgb(175, 24, 5)},
+	{block.CoalBlock{}, rgb(16, 15, 15)},
+	{block.CopperBlock{}, rgb(192, 107, 79)},
+}
+
+func rgb(r, g, b uint8) color.RGBA {
+	return color.RGBA{R: r, G: g, B: b, A: 0xFF}
+}
+
+// Nearest returns the block whose colour is closest to c.
+// It returns nil if the table is empty.
+func (t ColorTable) Nearest(c color.Color) block.Block {
+	target := color.RGBAModel.Convert(c).(color.RGBA)
+	var (
+		best     block.Block
+		bestDist = -1
+	)
+	for _, e := range t {
+		d := colorDistance(target, e.Color)
+		if bestDist < 0 || d < bestDist {
+			best, bestDist = e.Block, d
+		}
+	}
+	return best
+}
+
+// ColorOf returns the colour of the block with the same ID as b.
+func (t ColorTable) ColorOf(b block.Block) (color.RGBA, bool) {
+	id := b.ID()
+	for _, e := range t {
+		if e.Block.ID() == id {
+			return e.Color, true
+		}
+	}
+	return color.RGBA{}, false
+}
+
+// colorDistance is the "redmean" weighted euclidean distance, which tracks
+// human perception much better than plain RGB distance at almost no cost.
+func colorDistance(a, b color.RGBA) int {
+	rm := (int(a.R) + int(b.R)) / 2
+	dr := int(a.R) - int(b.R)
+	dg := int(a.G) - int(b.G)
+	db := int(a.B) - int(b.B)
+	return ((512+rm)*dr*dr)>>8 + 4*dg*dg + ((767-rm)*db*db)>>8
+}
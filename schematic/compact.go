@@ -0,0 +1,56 @@
+package schematic
+
+// Compact drops the palette entries no block uses any more, merges entries
+// of the same state and shrinks the block storage to the fewest bits per
+// block. Air stays at index 0. It also recounts MetaData.TotalBlocks.
+//
+// Encode, Litematic and Nbt compact the project before writing it.
+func (p *Project) Compact() {
+	p.mapStates(nil)
+}
+
+// mapStates replaces every state s of the palette with f(s), or keeps it if f
+// is nil, then compacts the palette and storage as Compact does. It returns
+// the number of blocks whose state changed.
+func (p *Project) mapStates(f func(BlockState) BlockState) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.palette.Lock()
+	defer p.palette.Unlock()
+
+	count := p.blocks.count(len(p.palette.palette))
+
+	// air stays at index 0 whether or not a block uses it
+	palette := []BlockState{Air}
+	m := map[BlockState]int{Air: 0}
+	remap := make([]int, len(p.palette.palette))
+	changed, total := 0, 0
+	for i, s := range p.palette.palette {
+		if count[i] == 0 {
+			continue
+		}
+		if f != nil {
+			if n := f(s); n != s {
+				changed += count[i]
+				s = n
+			}
+		}
+		if s.Name != air {
+			total += count[i]
+		}
+		if id, ok := m[s]; ok {
+			remap[i] = id
+			continue
+		}
+		m[s] = len(palette)
+		remap[i] = len(palette)
+		palette = append(palette, s)
+	}
+
+	p.blocks.remap(remap, len(palette))
+	p.writes.Add(1)
+	p.palette.palette = palette
+	p.palette.paletteMap = m
+	p.MetaData.TotalBlocks = int32(total)
+	return changed
+}
@@ -0,0 +1,56 @@
+package schematic
+
+import (
+	"bytes"
+	"github.com/Tnze/go-mc/level/block"
+	"testing"
+)
+
+func TestCompact(t *testing.T) {
+	p := randomProject(6, 6, 6)
+	before := make(map[[3]int]BlockState)
+	p.ForEachBlock(func(x, y, z int, s BlockState) {
+		before[[3]int{x, y, z}] = s
+	})
+	// leave only stone and air, the palette keeps all the wool
+	p.Replace(p.Bounds(), Not(Names("stone")), Single(block.Air{}))
+	if len(p.Palette()) != 19 || p.blocks.dense().BitsPerEntry() != 5 {
+		t.Fatalf("Error, palette before compact: %d entries, %d bits", len(p.Palette()), p.blocks.dense().BitsPerEntry())
+	}
+	total := p.MetaData.TotalBlocks
+	p.Compact()
+	if len(p.Palette()) != 2 || p.Palette()[0].Name != air || p.blocks.dense().BitsPerEntry() != defaultBits {
+		t.Fatalf("Error, palette after compact: %v, %d bits", p.Palette(), p.blocks.dense().BitsPerEntry())
+	}
+	if p.MetaData.TotalBlocks != total {
+		t.Fatalf("Error, total blocks: %d, want %d", p.MetaData.TotalBlocks, total)
+	}
+	for pos, s := range before {
+		got := p.GetBlock(pos[0], pos[1], pos[2])
+		if (s.Name == "minecraft:stone") != (got.Name == "minecraft:stone") {
+			t.Fatalf("Error, block at %v: %v, was %v", pos, got, s)
+		}
+	}
+
+	// states made equal by ChangeMaterial are merged
+	p.SetBlock(0, 0, 0, block.Dirt{})
+	p.ChangeMaterial(block.Dirt{}, block.Stone{})
+	p.Compact()
+	if len(p.Palette()) != 2 {
+		t.Fatalf("Error, palette after merge: %v", p.Palette())
+	}
+
+	var buf bytes.Buffer
+	p.SetBlock(1, 1, 1, block.Glass{})
+	p.SetBlock(1, 1, 1, block.Air{})
+	if err := p.Encode(&buf); err != nil {
+		t.Fatalf("Error, encode: %v", err)
+	}
+	l, err := LoadFromLitematic(&buf)
+	if err != nil {
+		t.Fatalf("Error, load: %v", err)
+	}
+	if len(l.Palette()) != 2 || l.GetBlock(0, 0, 0).Name != "minecraft:stone" {
+		t.Fatalf("Error, encoded palette: %v", l.Palette())
+	}
+}
@@ -0,0 +1,52 @@
+package schematic
+
+import (
+	"github.com/Tnze/go-mc/nbt"
+	"math"
+)
+
+// Crop returns a new project with the blocks, block entities and entities of p
+// inside box, moved so that the corner box.Min is at 0, 0, 0. The part of
+// box outside p is left out.
+func (p *Project) Crop(box Box) *Project {
+	box = box.Intersect(p.Bounds())
+	var size Vec3D
+	if !box.Empty() {
+		size = Vec3D{box.Max.X - box.Min.X + 1, box.Max.Y - box.Min.Y + 1, box.Max.Z - box.Min.Z + 1}
+	}
+	c := NewProject(p.RegionName, int(size.X), int(size.Y), int(size.Z),
+		WithAuthor(p.MetaData.Author), WithDescription(p.MetaData.Description),
+		WithDataVersion(int(p.MinecraftDataVersion)), WithVersion(int(p.Version)))
+	if box.Empty() {
+		return c
+	}
+	c.mu.Lock()
+	p.each(box, true, func(x, y, z int, s BlockState) bool {
+		c.setID(x-int(box.Min.X), y-int(box.Min.Y), z-int(box.Min.Z), c.palette.id(s))
+		return true
+	})
+	c.mu.Unlock()
+
+	p.mu.RLock()
+	for v, m := range p.blockEntities {
+		if box.Contains(int(v.X), int(v.Y), int(v.Z)) {
+			if c.blockEntities == nil {
+				c.blockEntities = make(map[Vec3D]nbt.RawMessage)
+			}
+			c.blockEntities[Vec3D{v.X - box.Min.X, v.Y - box.Min.Y, v.Z - box.Min.Z}] = m
+		}
+	}
+	p.mu.RUnlock()
+
+	for _, e := range p.entity.entity {
+		pos, err := entityPos(e)
+		if err != nil || !box.Contains(int(math.Floor(pos[0])), int(math.Floor(pos[1])), int(math.Floor(pos[2]))) {
+			continue
+		}
+		pos = [3]float64{pos[0] - float64(box.Min.X), pos[1] - float64(box.Min.Y), pos[2] - float64(box.Min.Z)}
+		if e, err := withEntityPos(e, pos); err == nil {
+			c.AddEntity(e)
+		}
+	}
+	return c
+}
@@ -0,0 +1,144 @@
+package schematic
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// packFormats maps the first data version of a release to the data pack
+// format of pack.mcmeta.
+var packFormats = []struct {
+	dataVersion int32
+	format      int
+}{
+	{1519, 4},  // 1.13
+	{2225, 5},  // 1.15
+	{2578, 6},  // 1.16.2
+	{2724, 7},  // 1.17
+	{2860, 8},  // 1.18
+	{2975, 9},  // 1.18.2
+	{3105, 10}, // 1.19
+	{3337, 12}, // 1.19.4
+	{3463, 15}, // 1.20
+	{3578, 18}, // 1.20.2
+	{3698, 26}, // 1.20.3
+	{3837, 41}, // 1.20.5
+	{3953, 48}, // 1.21
+	{4080, 57}, // 1.21.2
+	{4189, 61}, // 1.21.4
+	{4325, 71}, // 1.21.5
+	{4435, 80}, // 1.21.6
+	{4438, 81}, // 1.21.7
+}
+
+// PackFormat returns the data pack format of the Minecraft version with the
+// data version v. Versions before data packs get the first format and
+// versions after the table the last.
+func PackFormat(dataVersion int32) int {
+	i := sort.Search(len(packFormats), func(i int) bool { return packFormats[i].dataVersion > dataVersion })
+	if i == 0 {
+		return packFormats[0].format
+	}
+	return packFormats[i-1].format
+}
+
+// packDirs returns the structure and function directories of the pack
+// format, 1.21 dropped the plural.
+func packDirs(format int) (structure, function string) {
+	if format >= 48 {
+		return "structure", "function"
+	}
+	return "structures", "functions"
+}
+
+var (
+	namespacePattern = regexp.MustCompile(`^[a-z0-9_.-]+$`)
+	pathPattern      = regexp.MustCompile(`^[a-z0-9_.-]+(/[a-z0-9_.-]+)*$`)
+)
+
+// validResource checks the namespace and path of a resource location.
+func validResource(namespace, path string) error {
+	if !namespacePattern.MatchString(namespace) {
+		return fmt.Errorf("invalid namespace %q, only a-z, 0-9, _, . and - are allowed", namespace)
+	}
+	if !pathPattern.MatchString(path) {
+		return fmt.Errorf("invalid resource path %q, only a-z, 0-9, _, ., - and / are allowed", path)
+	}
+	return nil
+}
+
+// packWriter writes the files of a data pack.
+type packWriter interface {
+	//create returns a writer for the file at name, a slash separated path
+	//in the pack, that is closed after the file is written
+	create(name string) (io.WriteCloser, error)
+}
+
+// dirPack writes a data pack to a directory.
+type dirPack string
+
+func (d dirPack) create(name string) (io.WriteCloser, error) {
+	path := filepath.Join(string(d), filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	return os.Create(path)
+}
+
+// zipPack writes a data pack to a zip file.
+type zipPack struct {
+	*zip.Writer
+}
+
+func (z zipPack) create(name string) (io.WriteCloser, error) {
+	w, err := z.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return nopCloser{w}, nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// writePackFile writes a file of the pack with fn.
+func writePackFile(w packWriter, name string, fn func(io.Writer) error) error {
+	f, err := w.create(name)
+	if err != nil {
+		return err
+	}
+	if err := fn(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writePackJSON writes v as an indented JSON file of the pack.
+func writePackJSON(w packWriter, name string, v any) error {
+	return writePackFile(w, name, func(f io.Writer) error {
+		e := json.NewEncoder(f)
+		e.SetIndent("", "  ")
+		return e.Encode(v)
+	})
+}
+
+// writePackMeta writes pack.mcmeta.
+func writePackMeta(w packWriter, format int, description string) error {
+	type pack struct {
+		PackFormat  int    `json:"pack_format"`
+		Description string `json:"description"`
+	}
+	return writePackJSON(w, "pack.mcmeta", struct {
+		Pack pack `json:"pack"`
+	}{pack{format, description}})
+}
@@ -0,0 +1,76 @@
+package schematic
+
+import "context"
+
+// Fill places pat at every position of box matching mask, or at every position
+// if mask is nil, and returns the number of blocks that changed. The mask is
+// evaluated on the project as it was before the fill, so offset masks don't
+// see blocks placed by the same call.
+func (p *Project) Fill(box Box, pat Pattern, mask Mask) int {
+	n, _ := p.fill(box, pat, mask, nil)
+	return n
+}
+
+// FillContext is Fill stopping when ctx is done, the progress counts the
+// positions of box tested against the mask. When it stops while placing
+// blocks, those already placed stay and are counted.
+func (p *Project) FillContext(ctx context.Context, box Box, pat Pattern, mask Mask, progress Progress) (int, error) {
+	return p.fill(box, pat, mask, newTracker(ctx, progress, int64(box.Intersect(p.Bounds()).Volume()), progressStep))
+}
+
+func (p *Project) fill(box Box, pat Pattern, mask Mask, t *tracker) (int, error) {
+	box = box.Intersect(p.Bounds())
+	if box.Empty() {
+		return 0, nil
+	}
+	// the mask is tested on every block before any is set, the matches are
+	// kept one bit per block of box. Without a mask only the progress is
+	// counted, every block is set.
+	var selected []uint64
+	if mask != nil {
+		selected = make([]uint64, (box.Volume()+63)/64)
+	}
+	i := 0
+	for y := int(box.Min.Y); y <= int(box.Max.Y); y++ {
+		for z := int(box.Min.Z); z <= int(box.Max.Z); z++ {
+			for x := int(box.Min.X); mask != nil && x <= int(box.Max.X); x++ {
+				if mask.Test(p, x, y, z) {
+					selected[i/64] |= 1 << (i % 64)
+				}
+				i++
+			}
+			if err := t.add(int(box.Max.X-box.Min.X) + 1); err != nil {
+				return 0, err
+			}
+		}
+	}
+	changed := 0
+	i = 0
+	for y := int(box.Min.Y); y <= int(box.Max.Y); y++ {
+		for z := int(box.Min.Z); z <= int(box.Max.Z); z++ {
+			for x := int(box.Min.X); x <= int(box.Max.X); x++ {
+				n := i
+				i++
+				if selected != nil && selected[n/64]&(1<<(n%64)) == 0 {
+					continue
+				}
+				s := pat.At(x, y, z)
+				if p.GetBlock(x, y, z) == s {
+					continue
+				}
+				p.SetBlock(x, y, z, s.Properties)
+				changed++
+			}
+			if err := t.err(); err != nil {
+				return changed, err
+			}
+		}
+	}
+	return changed, nil
+}
+
+// Replace places to at every position of box matching from and returns the
+// number of blocks that changed.
+func (p *Project) Replace(box Box, from Mask, to Pattern) int {
+	return p.Fill(box, to, from)
+}
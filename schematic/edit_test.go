@@ -0,0 +1,38 @@
+package schematic
+
+import (
+	"github.com/Tnze/go-mc/level/block"
+	"testing"
+)
+
+func TestFillReplace(t *testing.T) {
+	p := NewProject("edit", 8, 4, 8)
+	if n := p.Fill(NewBox(0, 0, 0, 7, 0, 7), Single(block.Stone{}), nil); n != 64 {
+		t.Fatalf("Error, filled %d blocks, want 64", n)
+	}
+	if n := p.Fill(NewBox(-5, 0, -5, 100, 0, 100), Single(block.Stone{}), nil); n != 0 {
+		t.Fatalf("Error, filling the same block changed %d blocks", n)
+	}
+
+	// grass on the stone that has air above it, like a top soil pass
+	p.SetBlock(3, 1, 3, block.OakLog{Axis: block.Y})
+	n := p.Replace(p.Bounds(), And(Names("stone"), Offset(0, 1, 0, Not(Existing()))), Single(block.GrassBlock{}))
+	if n != 63 || p.GetBlock(3, 0, 3).Name != "minecraft:stone" {
+		t.Fatalf("Error, replaced %d blocks, want 63", n)
+	}
+	if p.MetaData.TotalBlocks != 65 {
+		t.Fatalf("Error, total blocks: %d, want 65", p.MetaData.TotalBlocks)
+	}
+
+	p.SetBlock(0, 1, 0, block.OakStairs{Facing: block.North})
+	p.SetBlock(1, 1, 0, block.OakStairs{Facing: block.South})
+	if n := p.Replace(p.Bounds(), Property("facing", "north"), Single(block.Air{})); n != 1 || p.GetBlock(1, 1, 0).Name != "minecraft:oak_stairs" {
+		t.Fatalf("Error, property mask replaced %d blocks", n)
+	}
+	if n := p.Replace(p.Bounds(), Or(BlockTag("#minecraft:logs"), BlockTag("stairs")), Single(block.Air{})); n != 2 {
+		t.Fatalf("Error, tag mask replaced %d blocks, want 2", n)
+	}
+	if p.MetaData.TotalBlocks != 64 {
+		t.Fatalf("Error, total blocks: %d, want 64", p.MetaData.TotalBlocks)
+	}
+}
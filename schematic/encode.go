@@ -0,0 +1,156 @@
+package schematic
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"github.com/Tnze/go-mc/nbt"
+	"io"
+	"math/bits"
+	"time"
+)
+
+// writeLitematic writes the uncompressed NBT of p.Litematic() with the
+// metadata and versions of h. The BlockStates longs are packed while they are written,
+// so only a row of blocks is held in memory besides the project itself, and
+// the dense layout of a sparse project is never built.
+func (p *Project) writeLitematic(w io.Writer, h LitematicHeader, t *tracker) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	palette := p.paletteSnapshot()
+	enc := nbt.NewEncoder(w)
+	if err := writeTag(w, nbt.TagCompound, ""); err != nil {
+		return err
+	}
+	if err := enc.Encode(h.Metadata, "Metadata"); err != nil {
+		return err
+	}
+	if err := enc.Encode(h.MinecraftDataVersion, "MinecraftDataVersion"); err != nil {
+		return err
+	}
+	if err := enc.Encode(h.Version, "Version"); err != nil {
+		return err
+	}
+	if err := writeTag(w, nbt.TagCompound, "Regions"); err != nil {
+		return err
+	}
+	if err := writeTag(w, nbt.TagCompound, p.RegionName); err != nil {
+		return err
+	}
+	// the fields of Region, in order
+	for _, f := range []struct {
+		name  string
+		value any
+	}{
+		{"BlockStatePalette", palette},
+		{"TileEntities", p.tileEntities()},
+		{"Entities", EntityList(p.entity.entity)},
+		{"Position", Vec3D{}},
+		{"Size", p.regionSize},
+	} {
+		if err := enc.Encode(f.value, f.name); err != nil {
+			return err
+		}
+	}
+	if err := p.writeBlockStates(w, bits.Len(uint(len(palette)-1)), t); err != nil {
+		return err
+	}
+	// end of the region, the regions and the root compound
+	_, err := w.Write([]byte{nbt.TagEnd, nbt.TagEnd, nbt.TagEnd})
+	return err
+}
+
+// writeBlockStates writes the BlockStates long array, packing the palette
+// index of every block in bitsPerEntry bits like BitArray.
+func (p *Project) writeBlockStates(w io.Writer, bitsPerEntry int, t *tracker) error {
+	bitsPerEntry = max(defaultBits, bitsPerEntry)
+	size := p.Size()
+	entries := int(size.X) * int(size.Y) * int(size.Z)
+	if err := writeTag(w, nbt.TagLongArray, "BlockStates"); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, int32((entries*bitsPerEntry+63)/64)); err != nil {
+		return err
+	}
+
+	var buf [8]byte
+	var long uint64
+	var used int // bits of long already used
+	flush := func() error {
+		binary.BigEndian.PutUint64(buf[:], long)
+		_, err := w.Write(buf[:])
+		return err
+	}
+	ids := make([]int, size.X)
+	for y := 0; y < int(size.Y); y++ {
+		for z := 0; z < int(size.Z); z++ {
+			p.blocks.row(0, y, z, ids)
+			for _, id := range ids {
+				long |= uint64(id) << used
+				used += bitsPerEntry
+				if used < 64 {
+					continue
+				}
+				if err := flush(); err != nil {
+					return err
+				}
+				used -= 64
+				// the rest of an entry spanning two longs
+				long = uint64(id) >> (bitsPerEntry - used)
+			}
+			if err := t.add(len(ids)); err != nil {
+				return err
+			}
+		}
+	}
+	if used > 0 {
+		return flush()
+	}
+	return nil
+}
+
+func writeTag(w io.Writer, tagType byte, name string) error {
+	if _, err := w.Write([]byte{tagType}); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, int16(len(name))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, name)
+	return err
+}
+
+// Encode writes the project as a gzipped litematic file. The file is the
+// same as encoding p.Litematic(), but it is written as it goes so very large
+// projects don't need twice their memory. opts change the metadata and
+// versions written, not those of the project.
+func (p *Project) Encode(w io.Writer, opts ...Option) error {
+	return p.encode(w, nil, opts)
+}
+
+// EncodeContext is Encode stopping when ctx is done, what was written to w
+// is then not a valid file.
+func (p *Project) EncodeContext(ctx context.Context, w io.Writer, progress Progress, opts ...Option) error {
+	return p.encode(w, newTracker(ctx, progress, int64(p.Bounds().Volume()), progressStep), opts)
+}
+
+func (p *Project) encode(w io.Writer, t *tracker, opts []Option) error {
+	p.Compact()
+	h := LitematicHeader{
+		Metadata:             p.MetaData,
+		MinecraftDataVersion: p.MinecraftDataVersion,
+		Version:              p.Version,
+	}
+	h.Metadata.TimeModified = time.Now().UnixMilli()
+	newOptions(opts).apply(&h, false)
+	gw := gzip.NewWriter(w)
+	bw := bufio.NewWriter(gw)
+	if err := p.writeLitematic(bw, h, t); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
@@ -0,0 +1,40 @@
+package schematic
+
+import (
+	"bytes"
+	"github.com/Tnze/go-mc/level/block"
+	"github.com/Tnze/go-mc/nbt"
+	"testing"
+)
+
+func TestStreamingEncoder(t *testing.T) {
+	for _, p := range []*Project{randomProject(13, 7, 11), NewSparseProject("sparse", 40, 9, 21)} {
+		p.SetBlock(20%p.XRange(), 3, 5, block.OakStairs{Facing: block.East})
+		// 19 states take 5 bits, so some entries span two longs
+		l := p.Litematic()
+		var want, got bytes.Buffer
+		if err := nbt.NewEncoder(&want).Encode(l, ""); err != nil {
+			t.Fatalf("Error, encode: %v", err)
+		}
+		if err := p.writeLitematic(&got, LitematicHeader{Metadata: l.Metadata, MinecraftDataVersion: l.MinecraftDataVersion, Version: l.Version}, nil); err != nil {
+			t.Fatalf("Error, streaming encode: %v", err)
+		}
+		if !bytes.Equal(want.Bytes(), got.Bytes()) {
+			t.Fatalf("Error, %s: streamed %d bytes differ from the %d bytes of the encoder", p.RegionName, got.Len(), want.Len())
+		}
+
+		var file bytes.Buffer
+		if err := p.Encode(&file); err != nil {
+			t.Fatalf("Error, encode: %v", err)
+		}
+		q, err := LoadFromLitematic(&file)
+		if err != nil {
+			t.Fatalf("Error, load: %v", err)
+		}
+		p.ForEachBlock(func(x, y, z int, s BlockState) {
+			if q.GetBlock(x, y, z) != s {
+				t.Fatalf("Error, block at %d, %d, %d: %v, want %v", x, y, z, q.GetBlock(x, y, z), s)
+			}
+		})
+	}
+}
@@ -1,5 +1,12 @@
 package schematic
 
+import (
+	"encoding/binary"
+	"github.com/Tnze/go-mc/nbt"
+	"io"
+	"reflect"
+)
+
 type ID struct {
 	ID string `nbt:"id"`
 }
@@ -21,11 +28,12 @@ type GlowItemFrame struct {
 	Item           FrameItem `nbt_omitempty:"true"`
 	ItemDropChance float32   `nbt_omitempty:"true"`
 	ItemRotation   uint8     `nbt_omitempty:"true"`
-	Motion         []float64 `nbt_type:"list"`
+	// slices of floats encode as lists, the nbt ,list tag is only for arrays
+	Motion         []float64
 	OnGround       uint8
 	PortalCooldown int32
-	Pos            []float64 `nbt_type:"list"`
-	Rotation       []float32 `nbt_type:"list"`
+	Pos            []float64
+	Rotation       []float32
 	TileX          int32
 	TileY          int32
 	TileZ          int32
@@ -48,3 +56,117 @@ func (e GlowItemFrame) ID() string { return "minecraft:glow_item_frame" }
 var ByID = map[string]Entity{
 	"minecraft:glow_item_frame": GlowItemFrame{},
 }
+
+// RawEntity is an entity of a type without a struct in ByID, kept as its NBT
+// compound so it is written back unchanged.
+type RawEntity struct {
+	nbt.RawMessage
+}
+
+func (e RawEntity) ID() string {
+	var id ID
+	_ = e.Unmarshal(&id)
+	return id.ID
+}
+
+// EntityList is a list of entities. Its elements are encoded one by one so
+// that a RawEntity is written as its compound.
+type EntityList []Entity
+
+func (l EntityList) TagType() byte { return nbt.TagList }
+
+func (l EntityList) MarshalNBT(w io.Writer) error {
+	// an empty list has no element type, as the nbt encoder writes []Entity
+	t := byte(nbt.TagEnd)
+	if len(l) > 0 {
+		t = nbt.TagCompound
+	}
+	if _, err := w.Write([]byte{t}); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, int32(len(l))); err != nil {
+		return err
+	}
+	for _, e := range l {
+		m, err := entityMessage(e)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(m.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseEntity decodes the entity compound m to the type of its id in ByID, or
+// to a RawEntity.
+func parseEntity(m nbt.RawMessage) Entity {
+	var id ID
+	if err := m.Unmarshal(&id); err != nil {
+		return RawEntity{m}
+	}
+	e, ok := ByID[id.ID]
+	if !ok {
+		return RawEntity{m}
+	}
+	v := reflect.New(reflect.TypeOf(e))
+	if err := m.Unmarshal(v.Interface()); err != nil {
+		return RawEntity{m}
+	}
+	return v.Elem().Interface().(Entity)
+}
+
+// entityMessage encodes e to NBT with its id, which structs like
+// GlowItemFrame leave empty.
+func entityMessage(e Entity) (nbt.RawMessage, error) {
+	m, err := rawMessage(e)
+	if err != nil {
+		return m, err
+	}
+	var id ID
+	if err := m.Unmarshal(&id); err != nil || id.ID != "" {
+		return m, err
+	}
+	fields, err := compoundFields(m)
+	if err != nil {
+		return m, err
+	}
+	if fields["id"], err = rawMessage(e.ID()); err != nil {
+		return m, err
+	}
+	return compoundOf(fields), nil
+}
+
+// entityPos returns the Pos of an entity.
+func entityPos(e Entity) ([3]float64, error) {
+	m, err := entityMessage(e)
+	if err != nil {
+		return [3]float64{}, err
+	}
+	var pos struct {
+		Pos []float64
+	}
+	if err := m.Unmarshal(&pos); err != nil {
+		return [3]float64{}, err
+	}
+	var p [3]float64
+	copy(p[:], pos.Pos)
+	return p, nil
+}
+
+// withEntityPos returns e moved to pos.
+func withEntityPos(e Entity, pos [3]float64) (Entity, error) {
+	m, err := entityMessage(e)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := compoundFields(m)
+	if err != nil {
+		return nil, err
+	}
+	if fields["Pos"], err = rawMessage(pos[:]); err != nil {
+		return nil, err
+	}
+	return parseEntity(compoundOf(fields)), nil
+}
@@ -0,0 +1,220 @@
+package schematic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+)
+
+type gltfDocument struct {
+	Asset       gltfAsset        `json:"asset"`
+	Scene       int              `json:"scene"`
+	Scenes      []gltfScene      `json:"scenes"`
+	Nodes       []gltfNode       `json:"nodes,omitempty"`
+	Meshes      []gltfMesh       `json:"meshes,omitempty"`
+	Materials   []gltfMaterial   `json:"materials,omitempty"`
+	Accessors   []gltfAccessor   `json:"accessors,omitempty"`
+	BufferViews []gltfBufferView `json:"bufferViews,omitempty"`
+	Buffers     []gltfBuffer     `json:"buffers,omitempty"`
+}
+
+type gltfAsset struct {
+	Version   string `json:"version"`
+	Generator string `json:"generator"`
+}
+
+type gltfScene struct {
+	Nodes []int `json:"nodes,omitempty"`
+}
+
+type gltfNode struct {
+	Mesh int `json:"mesh"`
+}
+
+type gltfMesh struct {
+	Primitives []gltfPrimitive `json:"primitives"`
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    int            `json:"indices"`
+	Material   int            `json:"material"`
+}
+
+type gltfMaterial struct {
+	Name                 string  `json:"name"`
+	PbrMetallicRoughness gltfPBR `json:"pbrMetallicRoughness"`
+}
+
+type gltfPBR struct {
+	BaseColorFactor [4]float64 `json:"baseColorFactor"`
+	MetallicFactor  float64    `json:"metallicFactor"`
+	RoughnessFactor float64    `json:"roughnessFactor"`
+}
+
+type gltfAccessor struct {
+	BufferView    int       `json:"bufferView"`
+	ComponentType int       `json:"componentType"`
+	Count         int       `json:"count"`
+	Type          string    `json:"type"`
+	Min           []float32 `json:"min,omitempty"`
+	Max           []float32 `json:"max,omitempty"`
+}
+
+type gltfBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	Target     int `json:"target"`
+}
+
+type gltfBuffer struct {
+	ByteLength int `json:"byteLength"`
+}
+
+const (
+	gltfFloat        = 5126
+	gltfUnsignedInt  = 5125
+	gltfArrayBuffer  = 34962
+	gltfElementArray = 34963
+)
+
+// WriteGLB writes the mesh as a binary glTF 2.0 file, with one primitive and
+// one material for every material name in the mesh. A mesh without triangles
+// is an empty scene.
+func (m *Mesh) WriteGLB(w io.Writer) error {
+	doc := gltfDocument{
+		Asset:  gltfAsset{Version: "2.0", Generator: "go-litematica-tools"},
+		Scenes: []gltfScene{{}},
+	}
+	if len(m.Triangles) > 0 {
+		// glTF has no empty meshes or buffers
+		doc.Scenes[0].Nodes = []int{0}
+		doc.Nodes = []gltfNode{{Mesh: 0}}
+		doc.Meshes = []gltfMesh{{}}
+	}
+	var bin bytes.Buffer
+
+	type vertex struct{ pos, normal Vec3 }
+	type primitive struct {
+		index    map[vertex]uint32
+		vertices []vertex
+		indices  []uint32
+	}
+	var (
+		order      []string
+		primitives = make(map[string]*primitive)
+	)
+	for _, t := range m.Triangles {
+		pr, ok := primitives[t.Material]
+		if !ok {
+			pr = &primitive{index: make(map[vertex]uint32)}
+			primitives[t.Material] = pr
+			order = append(order, t.Material)
+			c := defaultMeshColor
+			if t.HasColor {
+				c = t.Color
+			}
+			doc.Materials = append(doc.Materials, gltfMaterial{
+				Name: t.Material,
+				PbrMetallicRoughness: gltfPBR{
+					BaseColorFactor: [4]float64{srgbToLinear(c.R), srgbToLinear(c.G), srgbToLinear(c.B), float64(c.A) / 255},
+					RoughnessFactor: 1,
+				},
+			})
+		}
+		n := t.normal()
+		for _, p := range t.V {
+			v := vertex{p, n}
+			i, ok := pr.index[v]
+			if !ok {
+				i = uint32(len(pr.vertices))
+				pr.index[v] = i
+				pr.vertices = append(pr.vertices, v)
+			}
+			pr.indices = append(pr.indices, i)
+		}
+	}
+
+	addView := func(data []byte, target int) int {
+		doc.BufferViews = append(doc.BufferViews, gltfBufferView{ByteOffset: bin.Len(), ByteLength: len(data), Target: target})
+		bin.Write(data)
+		return len(doc.BufferViews) - 1
+	}
+	for mat, name := range order {
+		pr := primitives[name]
+		pos := make([]byte, 0, len(pr.vertices)*12)
+		nor := make([]byte, 0, len(pr.vertices)*12)
+		lo := []float32{math.MaxFloat32, math.MaxFloat32, math.MaxFloat32}
+		hi := []float32{-math.MaxFloat32, -math.MaxFloat32, -math.MaxFloat32}
+		for _, v := range pr.vertices {
+			for k, c := range [3]float32{float32(v.pos.X), float32(v.pos.Y), float32(v.pos.Z)} {
+				lo[k], hi[k] = float32(math.Min(float64(lo[k]), float64(c))), float32(math.Max(float64(hi[k]), float64(c)))
+				pos = binary.LittleEndian.AppendUint32(pos, math.Float32bits(c))
+			}
+			nor = binary.LittleEndian.AppendUint32(nor, math.Float32bits(float32(v.normal.X)))
+			nor = binary.LittleEndian.AppendUint32(nor, math.Float32bits(float32(v.normal.Y)))
+			nor = binary.LittleEndian.AppendUint32(nor, math.Float32bits(float32(v.normal.Z)))
+		}
+		idx := make([]byte, 0, len(pr.indices)*4)
+		for _, i := range pr.indices {
+			idx = binary.LittleEndian.AppendUint32(idx, i)
+		}
+
+		doc.Accessors = append(doc.Accessors,
+			gltfAccessor{BufferView: addView(pos, gltfArrayBuffer), ComponentType: gltfFloat, Count: len(pr.vertices), Type: "VEC3", Min: lo, Max: hi},
+			gltfAccessor{BufferView: addView(nor, gltfArrayBuffer), ComponentType: gltfFloat, Count: len(pr.vertices), Type: "VEC3"},
+			gltfAccessor{BufferView: addView(idx, gltfElementArray), ComponentType: gltfUnsignedInt, Count: len(pr.indices), Type: "SCALAR"},
+		)
+		a := len(doc.Accessors) - 3
+		doc.Meshes[0].Primitives = append(doc.Meshes[0].Primitives, gltfPrimitive{
+			Attributes: map[string]int{"POSITION": a, "NORMAL": a + 1},
+			Indices:    a + 2,
+			Material:   mat,
+		})
+	}
+	if bin.Len() > 0 {
+		doc.Buffers = []gltfBuffer{{ByteLength: bin.Len()}}
+	}
+
+	js, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	for len(js)%4 != 0 {
+		js = append(js, ' ')
+	}
+	for bin.Len()%4 != 0 {
+		bin.WriteByte(0)
+	}
+
+	// the BIN chunk is left out with the buffer
+	length := 20 + len(js)
+	if bin.Len() > 0 {
+		length += 8 + bin.Len()
+	}
+	out := make([]byte, 0, length)
+	out = binary.LittleEndian.AppendUint32(out, 0x46546C67) // "glTF"
+	out = binary.LittleEndian.AppendUint32(out, 2)
+	out = binary.LittleEndian.AppendUint32(out, uint32(length))
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(js)))
+	out = binary.LittleEndian.AppendUint32(out, 0x4E4F534A) // "JSON"
+	out = append(out, js...)
+	if bin.Len() > 0 {
+		out = binary.LittleEndian.AppendUint32(out, uint32(bin.Len()))
+		out = binary.LittleEndian.AppendUint32(out, 0x004E4942) // "BIN"
+		out = append(out, bin.Bytes()...)
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+func srgbToLinear(c uint8) float64 {
+	f := float64(c) / 255
+	if f <= 0.04045 {
+		return f / 12.92
+	}
+	return math.Pow((f+0.055)/1.055, 2.4)
+}
@@ -0,0 +1,117 @@
+package schematic
+
+// BlockFunc is called with the position and the state of a block.
+type BlockFunc func(x, y, z int, s BlockState)
+
+// ForEachBlock calls fn for every block of the project, air included, in
+// storage order: x first, then z, then y.
+//
+// The blocks are decoded one after another without locking for each of them,
+// which is much faster than calling GetBlock in a loop. fn may set blocks of
+// the project, a block set ahead of the walk is seen when the walk gets to
+// it.
+func (p *Project) ForEachBlock(fn BlockFunc) {
+	p.ForEachInBox(p.Bounds(), fn)
+}
+
+// ForEachNonAir is like ForEachBlock but skips air.
+func (p *Project) ForEachNonAir(fn BlockFunc) {
+	p.each(p.Bounds(), true, func(x, y, z int, s BlockState) bool {
+		fn(x, y, z, s)
+		return true
+	})
+}
+
+// ForEachInLayer calls fn for every block of the layer at height y.
+func (p *Project) ForEachInLayer(y int, fn BlockFunc) {
+	p.ForEachInBox(NewBox(0, y, 0, p.XRange()-1, y, p.ZRange()-1), fn)
+}
+
+// ForEachInBox calls fn for every block of the project inside box, the part of
+// box outside the project is skipped.
+func (p *Project) ForEachInBox(box Box, fn BlockFunc) {
+	p.each(box, false, func(x, y, z int, s BlockState) bool {
+		fn(x, y, z, s)
+		return true
+	})
+}
+
+// each walks the blocks in box until fn returns false, it reports whether the
+// walk went to the end.
+func (p *Project) each(box Box, skipAir bool, fn func(x, y, z int, s BlockState) bool) bool {
+	box = box.Intersect(p.Bounds())
+	if box.Empty() {
+		return true
+	}
+	// rows are decoded under the read lock and fn is called after it is
+	// released, so fn may set blocks and other goroutines may write meanwhile.
+	// The rest of the row is decoded again when blocks were set.
+	ids := make([]int, box.Max.X-box.Min.X+1)
+	row := make([]BlockState, len(ids))
+	decode := func(x, y, z int) uint64 {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+		palette := p.paletteSnapshot()
+		n := int(box.Max.X) - x + 1
+		p.blocks.row(x, y, z, ids[:n])
+		for i, id := range ids[:n] {
+			row[len(row)-n+i] = palette[id]
+		}
+		return p.writes.Load()
+	}
+	for y := int(box.Min.Y); y <= int(box.Max.Y); y++ {
+		for z := int(box.Min.Z); z <= int(box.Max.Z); z++ {
+			writes := decode(int(box.Min.X), y, z)
+			for i := range row {
+				if w := p.writes.Load(); w != writes {
+					writes = decode(int(box.Min.X)+i, y, z)
+				}
+				if skipAir && row[i].Name == air {
+					continue
+				}
+				if !fn(int(box.Min.X)+i, y, z, row[i]) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+func (p *Project) paletteSnapshot() []BlockState {
+	p.palette.RLock()
+	defer p.palette.RUnlock()
+	return p.palette.palette
+}
+
+// bitReader decodes consecutive entries of a BitArray.
+type bitReader struct {
+	b *BitArray
+
+	bits int
+	mask uint64
+
+	//pos Bit offset of the next entry
+	pos int64
+}
+
+// seek moves to the entry at index, it must be called again after the
+// BitArray is resized.
+func (r *bitReader) seek(index int) {
+	r.bits = r.b.bitsPerEntry
+	r.mask = uint64(r.b.maxEntryValue)
+	r.pos = int64(index) * int64(r.bits)
+}
+
+func (r *bitReader) next() int {
+	i, off := int(r.pos>>6), uint(r.pos&0x3F)
+	r.pos += int64(r.bits)
+	if i >= len(r.b.data) {
+		return 0
+	}
+	v := uint64(r.b.data[i]) >> off
+	if off+uint(r.bits) > 64 && i+1 < len(r.b.data) {
+		v |= uint64(r.b.data[i+1]) << (64 - off)
+	}
+	return int(v & r.mask)
+}
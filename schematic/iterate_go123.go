@@ -0,0 +1,34 @@
+//go:build go1.23
+
+package schematic
+
+import "iter"
+
+// All returns an iterator over the positions and states of all the blocks of
+// the project, in the order of ForEachBlock.
+func (p *Project) All() iter.Seq2[Vec3D, BlockState] {
+	return p.InBox(p.Bounds())
+}
+
+// NonAir is like All but skips air.
+func (p *Project) NonAir() iter.Seq2[Vec3D, BlockState] {
+	return p.seq(p.Bounds(), true)
+}
+
+// Layer returns an iterator over the blocks of the layer at height y.
+func (p *Project) Layer(y int) iter.Seq2[Vec3D, BlockState] {
+	return p.InBox(NewBox(0, y, 0, p.XRange()-1, y, p.ZRange()-1))
+}
+
+// InBox returns an iterator over the blocks of the project inside box.
+func (p *Project) InBox(box Box) iter.Seq2[Vec3D, BlockState] {
+	return p.seq(box, false)
+}
+
+func (p *Project) seq(box Box, skipAir bool) iter.Seq2[Vec3D, BlockState] {
+	return func(yield func(Vec3D, BlockState) bool) {
+		p.each(box, skipAir, func(x, y, z int, s BlockState) bool {
+			return yield(Vec3D{int32(x), int32(y), int32(z)}, s)
+		})
+	}
+}
@@ -0,0 +1,38 @@
+//go:build go1.23
+
+package schematic
+
+import "testing"
+
+func TestIterators(t *testing.T) {
+	p := randomProject(5, 4, 3)
+	n := 0
+	for pos, s := range p.All() {
+		if s != p.GetBlock(int(pos.X), int(pos.Y), int(pos.Z)) {
+			t.Fatalf("Error, block at %v is wrong", pos)
+		}
+		n++
+	}
+	if n != 5*4*3 {
+		t.Fatalf("Error, %d blocks, want 60", n)
+	}
+	n = 0
+	for range p.NonAir() {
+		n++
+	}
+	if n != int(p.MetaData.TotalBlocks) {
+		t.Fatalf("Error, %d non air blocks, want %d", n, p.MetaData.TotalBlocks)
+	}
+	n = 0
+	for pos := range p.Layer(1) {
+		if pos.Y != 1 {
+			t.Fatalf("Error, layer gives %v", pos)
+		}
+		if n++; n == 3 {
+			break
+		}
+	}
+	if n != 3 {
+		t.Fatalf("Error, break didn't stop at 3")
+	}
+}
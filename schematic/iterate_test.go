@@ -0,0 +1,94 @@
+package schematic
+
+import (
+	"github.com/Tnze/go-mc/level/block"
+	"math/rand"
+	"testing"
+)
+
+// randomProject has enough different blocks for entries to span two longs.
+func randomProject(x, y, z int) *Project {
+	p := NewProject("iterate", x, y, z)
+	r := rand.New(rand.NewSource(1))
+	colors := []block.Block{block.Air{}, block.WhiteWool{}, block.OrangeWool{}, block.MagentaWool{}, block.LightBlueWool{},
+		block.YellowWool{}, block.LimeWool{}, block.PinkWool{}, block.GrayWool{}, block.LightGrayWool{}, block.CyanWool{},
+		block.PurpleWool{}, block.BlueWool{}, block.BrownWool{}, block.GreenWool{}, block.RedWool{}, block.BlackWool{},
+		block.OakLeaves{Distance: 3}, block.Stone{}}
+	for i := 0; i < x*y*z; i++ {
+		p.SetBlock(i%x, i/(x*z), i/x%z, colors[r.Intn(len(colors))])
+	}
+	return p
+}
+
+func TestForEachBlock(t *testing.T) {
+	p := randomProject(7, 5, 6)
+	count, nonAir := 0, 0
+	p.ForEachBlock(func(x, y, z int, s BlockState) {
+		if s != p.GetBlock(x, y, z) {
+			t.Fatalf("Error, block at %d, %d, %d: %v, want %v", x, y, z, s, p.GetBlock(x, y, z))
+		}
+		count++
+	})
+	p.ForEachNonAir(func(x, y, z int, s BlockState) {
+		if s.Name == air {
+			t.Fatalf("Error, ForEachNonAir gives air")
+		}
+		nonAir++
+	})
+	if count != 7*5*6 || nonAir != int(p.MetaData.TotalBlocks) {
+		t.Fatalf("Error, %d blocks and %d non air, want %d and %d", count, nonAir, 7*5*6, p.MetaData.TotalBlocks)
+	}
+
+	count = 0
+	p.ForEachInBox(NewBox(5, 3, 4, 10, -1, 10), func(x, y, z int, s BlockState) {
+		if x < 5 || y > 3 || z < 4 {
+			t.Fatalf("Error, %d, %d, %d is outside the box", x, y, z)
+		}
+		count++
+	})
+	if count != 2*4*2 {
+		t.Fatalf("Error, %d blocks in box, want 16", count)
+	}
+	p.ForEachInLayer(2, func(x, y, z int, s BlockState) {
+		if y != 2 {
+			t.Fatalf("Error, layer gives y = %d", y)
+		}
+	})
+}
+
+func TestForEachSetBlock(t *testing.T) {
+	p := NewProject("iterate", 4, 4, 4)
+	p.SetBlock(0, 0, 0, block.Stone{})
+	// every block set adds to the palette, so the storage grows while iterating
+	i := 0
+	p.ForEachBlock(func(x, y, z int, s BlockState) {
+		if s != p.GetBlock(x, y, z) {
+			t.Fatalf("Error, %d, %d, %d is %v, want %v", x, y, z, s, p.GetBlock(x, y, z))
+		}
+		p.SetBlock((x+1)%4, y, z, block.Candle{Candles: block.Integer(i%4 + 1), Lit: i%8 < 4})
+		i++
+	})
+	if p.blocks.dense().BitsPerEntry() < 4 {
+		t.Fatalf("Error, storage was not resized")
+	}
+}
+
+func BenchmarkForEachBlock(b *testing.B) {
+	p := randomProject(64, 64, 64)
+	b.Run("GetBlock", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for y := 0; y < p.YRange(); y++ {
+				for z := 0; z < p.ZRange(); z++ {
+					for x := 0; x < p.XRange(); x++ {
+						_ = p.GetBlock(x, y, z)
+					}
+				}
+			}
+		}
+	})
+	b.Run("ForEachBlock", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			p.ForEachBlock(func(x, y, z int, s BlockState) {})
+		}
+	})
+}
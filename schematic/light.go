@@ -0,0 +1,295 @@
+package schematic
+
+import (
+	"github.com/Tnze/go-mc/level/block"
+	"strconv"
+	"strings"
+)
+
+// MaxLight is the light level of the sky and of the brightest blocks.
+const MaxLight = 15
+
+// lightEmission is the light of blocks that always shine.
+var lightEmission = map[string]int{
+	"beacon": 15, "conduit": 15, "end_gateway": 15, "end_portal": 15, "fire": 15,
+	"glowstone": 15, "jack_o_lantern": 15, "lantern": 15, "lava": 15, "lava_cauldron": 15,
+	"sea_lantern": 15, "shroomlight": 15, "ochre_froglight": 15, "verdant_froglight": 15,
+	"pearlescent_froglight": 15, "end_rod": 14, "torch": 14, "wall_torch": 14,
+	"nether_portal": 11, "crying_obsidian": 10, "soul_fire": 10, "soul_lantern": 10,
+	"soul_torch": 10, "soul_wall_torch": 10, "enchanting_table": 7, "ender_chest": 7,
+	"glow_lichen": 7, "sculk_catalyst": 6, "amethyst_cluster": 5, "large_amethyst_bud": 4,
+	"magma_block": 3, "medium_amethyst_bud": 2, "small_amethyst_bud": 1, "brewing_stand": 1,
+	"brown_mushroom": 1, "dragon_egg": 1, "end_portal_frame": 1, "sculk_sensor": 1,
+	"calibrated_sculk_sensor": 1,
+}
+
+// litEmission is the light of blocks that shine while their lit property is
+// true.
+var litEmission = map[string]int{
+	"campfire": 15, "redstone_lamp": 15, "furnace": 13, "blast_furnace": 13, "smoker": 13,
+	"soul_campfire": 10, "redstone_ore": 9, "deepslate_redstone_ore": 9,
+	"redstone_torch": 7, "redstone_wall_torch": 7,
+}
+
+// emission returns the light level s gives off.
+func emission(s BlockState) int {
+	name := shortName(s)
+	if l, ok := lightEmission[name]; ok {
+		return l
+	}
+	lit := property(s.Properties, "lit") == "true"
+	if l, ok := litEmission[name]; ok && lit {
+		return l
+	}
+	count := func(p string) int {
+		n, _ := strconv.Atoi(property(s.Properties, p))
+		return n
+	}
+	switch {
+	case name == "light":
+		return count("level")
+	case strings.HasSuffix(name, "candle_cake"):
+		if lit {
+			return 3
+		}
+	case name == "candle" || strings.HasSuffix(name, "_candle"):
+		if lit {
+			return 3 * count("candles")
+		}
+	case name == "sea_pickle":
+		if property(s.Properties, "waterlogged") == "true" {
+			return 3 * (count("pickles") + 1)
+		}
+	case name == "respawn_anchor":
+		return []int{0, 3, 7, 11, 15}[clampInt(count("charges"), 0, 4)]
+	case name == "cave_vines" || name == "cave_vines_plant":
+		if property(s.Properties, "berries") == "true" {
+			return 14
+		}
+	}
+	return 0
+}
+
+// dimBlocks let light through but take one more level from it.
+var dimBlocks = map[string]bool{
+	"water": true, "bubble_column": true, "ice": true, "frosted_ice": true,
+	"slime_block": true, "honey_block": true, "cobweb": true, "powder_snow": true,
+}
+
+// opacity returns the light levels s takes from the light going through it,
+// beyond the level lost every block. Blocks that aren't full let light
+// through, though the game blocks it on the full faces of slabs and stairs.
+func opacity(s BlockState) int {
+	name := shortName(s)
+	switch {
+	case name == "tinted_glass" || isOpaqueFullBlock(s.Properties):
+		return MaxLight
+	case dimBlocks[name] || strings.HasSuffix(name, "_leaves") || property(s.Properties, "waterlogged") == "true":
+		return 1
+	}
+	return 0
+}
+
+// LightMap is the block light and sky light of every block of a project.
+type LightMap struct {
+	Size Vec3D
+
+	//Block Light from blocks, by Vec3D.getIndex
+	Block []uint8
+
+	//Sky Light from the sky above the project
+	Sky []uint8
+}
+
+// BlockLight returns the light from blocks at x, y, z, 0 out of the project.
+func (m *LightMap) BlockLight(x, y, z int) int {
+	if m.Size.outOfRange(x, y, z) {
+		return 0
+	}
+	return int(m.Block[m.Size.getIndex(x, y, z)])
+}
+
+// SkyLight returns the light from the sky at x, y, z, MaxLight out of the
+// project.
+func (m *LightMap) SkyLight(x, y, z int) int {
+	if m.Size.outOfRange(x, y, z) {
+		return MaxLight
+	}
+	return int(m.Sky[m.Size.getIndex(x, y, z)])
+}
+
+// Light returns the brighter of the block and sky light at x, y, z, the light
+// level of the game at noon.
+func (m *LightMap) Light(x, y, z int) int {
+	return max(m.BlockLight(x, y, z), m.SkyLight(x, y, z))
+}
+
+// Light computes the light of every block of the project. Block light
+// spreads from the blocks in lightEmission, sky light comes down from the top
+// of the project as if nothing were above it. No light comes in through the
+// sides and bottom of the project.
+func (p *Project) Light() *LightMap {
+	size := p.Size()
+	volume := int(size.X) * int(size.Y) * int(size.Z)
+	m := &LightMap{Size: size, Block: make([]uint8, volume), Sky: make([]uint8, volume)}
+	opacities := make([]uint8, volume)
+	var queue []int
+	p.ForEachBlock(func(x, y, z int, s BlockState) {
+		i := size.getIndex(x, y, z)
+		opacities[i] = uint8(opacity(s))
+		if l := emission(s); l > 0 {
+			m.Block[i] = uint8(l)
+			queue = append(queue, i)
+		}
+	})
+	spreadLight(size, m.Block, opacities, queue)
+
+	queue = queue[:0]
+	layer := int(size.X) * int(size.Z)
+	for c := 0; c < layer; c++ {
+		// straight down the sky light only loses what blocks take from it
+		l := MaxLight
+		for y := int(size.Y) - 1; y >= 0 && l > 0; y-- {
+			i := y*layer + c
+			l = max(l-int(opacities[i]), 0)
+			m.Sky[i] = uint8(l)
+			if l > 0 {
+				queue = append(queue, i)
+			}
+		}
+	}
+	spreadLight(size, m.Sky, opacities, queue)
+	return m
+}
+
+// spreadLight spreads the light of the blocks in queue to their neighbours,
+// losing a level every block and the opacity of the block it goes in.
+func spreadLight(size Vec3D, light, opacities []uint8, queue []int) {
+	sx, sz := int(size.X), int(size.Z)
+	layer := sx * sz
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		x, z, y := i%sx, i/sx%sz, i/layer
+		for _, o := range [6][3]int{{1, 0, 0}, {-1, 0, 0}, {0, 1, 0}, {0, -1, 0}, {0, 0, 1}, {0, 0, -1}} {
+			nx, ny, nz := x+o[0], y+o[1], z+o[2]
+			if size.outOfRange(nx, ny, nz) {
+				continue
+			}
+			n := size.getIndex(nx, ny, nz)
+			l := int(light[i]) - max(1, int(opacities[n]))
+			if l > int(light[n]) {
+				light[n] = uint8(l)
+				queue = append(queue, n)
+			}
+		}
+	}
+}
+
+// SpawnOptions changes what SpawnReport counts as spawnable.
+type SpawnOptions struct {
+	//Headroom Blocks free of collision a mob needs above the surface, 2 if 0
+	Headroom int
+
+	//IgnoreSky counts only block light, as the sky light doesn't stop
+	//monsters at night
+	IgnoreSky bool
+
+	//Marker Block marking the spots in Overlay, red carpet if nil
+	Marker block.Block
+}
+
+// SpawnReport lists where monsters can spawn in a project.
+type SpawnReport struct {
+	//Size of the project
+	Size Vec3D
+
+	//Surfaces Blocks monsters can spawn on, in storage order
+	Surfaces []Vec3D
+
+	Light *LightMap
+
+	marker      block.Block
+	dataVersion int32
+}
+
+// noSpawnBlocks have a full top face monsters can't spawn on.
+var noSpawnBlocks = map[string]bool{
+	"magma_block": true, "glass": true, "tinted_glass": true, "ice": true, "frosted_ice": true,
+	"beacon": true,
+}
+
+// spawnSurface reports whether monsters can stand on top of s.
+func spawnSurface(s BlockState) bool {
+	name := shortName(s)
+	if noSpawnBlocks[name] || strings.HasSuffix(name, "_leaves") || strings.HasSuffix(name, "stained_glass") {
+		return false
+	}
+	switch {
+	case strings.HasSuffix(name, "_slab"):
+		return property(s.Properties, "type") != "bottom"
+	case strings.HasSuffix(name, "_stairs"):
+		return property(s.Properties, "half") == "top"
+	}
+	return isFullBlock(s.Properties)
+}
+
+// spawnSpace reports whether a monster fits in the block space of s, which
+// has no collision, isn't a fluid and gives no redstone power.
+func spawnSpace(s BlockState) bool {
+	name := shortName(s)
+	switch name {
+	case "air", "cave_air", "void_air", "light", "structure_void", "torch", "wall_torch",
+		"soul_torch", "soul_wall_torch", "vine", "glow_lichen":
+		return property(s.Properties, "waterlogged") != "true"
+	case "cobweb", "sweet_berry_bush", "wither_rose", "fire", "soul_fire", "kelp", "kelp_plant",
+		"seagrass", "tall_seagrass":
+		return false
+	}
+	return plantBlocks[name] || strings.HasSuffix(name, "_sapling") || strings.HasSuffix(name, "_tulip") ||
+		strings.HasSuffix(name, "_sign") && property(s.Properties, "waterlogged") != "true"
+}
+
+// SpawnReport finds the surfaces monsters can spawn on: blocks with a full
+// top face, light level 0 in the block above and opt.Headroom blocks a mob
+// fits in above them. The block above a surface must be in the project, the
+// blocks above the project count as air.
+func (p *Project) SpawnReport(opt SpawnOptions) *SpawnReport {
+	if opt.Headroom <= 0 {
+		opt.Headroom = 2
+	}
+	if opt.Marker == nil {
+		opt.Marker = block.RedCarpet{}
+	}
+	r := &SpawnReport{Size: p.Size(), Light: p.Light(), marker: opt.Marker, dataVersion: p.MinecraftDataVersion}
+	p.ForEachNonAir(func(x, y, z int, s BlockState) {
+		if y+1 >= p.YRange() || !spawnSurface(s) {
+			return
+		}
+		l := r.Light.BlockLight(x, y+1, z)
+		if !opt.IgnoreSky {
+			l = r.Light.Light(x, y+1, z)
+		}
+		if l > 0 {
+			return
+		}
+		for h := 1; h <= opt.Headroom; h++ {
+			if !spawnSpace(p.GetBlock(x, y+h, z)) {
+				return
+			}
+		}
+		r.Surfaces = append(r.Surfaces, Vec3D{int32(x), int32(y), int32(z)})
+	})
+	return r
+}
+
+// Overlay returns a project of the size of the report with the marker block
+// in the space above every surface, to be shown on top of the analysed
+// project.
+func (r *SpawnReport) Overlay(name string) *Project {
+	p := NewProject(name, int(r.Size.X), int(r.Size.Y), int(r.Size.Z), WithDataVersion(int(r.dataVersion)))
+	for _, s := range r.Surfaces {
+		p.SetBlock(int(s.X), int(s.Y)+1, int(s.Z), r.marker)
+	}
+	return p
+}
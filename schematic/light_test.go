@@ -0,0 +1,74 @@
+package schematic
+
+import (
+	"github.com/Tnze/go-mc/level/block"
+	"testing"
+)
+
+func TestLight(t *testing.T) {
+	p := NewProject("light", 24, 8, 24)
+	p.Fill(NewBox(0, 0, 0, 23, 0, 23), Single(block.Stone{}), nil)
+	// a roof with a window
+	p.Fill(NewBox(0, 4, 0, 23, 4, 23), Single(block.Stone{}), nil)
+	p.SetBlock(2, 1, 22, block.Torch{})
+	p.SetBlock(1, 4, 1, block.Glass{})
+
+	m := p.Light()
+	if l := m.BlockLight(2, 1, 22); l != 14 {
+		t.Fatalf("Error, torch light %d, want 14", l)
+	}
+	if l := m.BlockLight(5, 1, 22); l != 11 {
+		t.Fatalf("Error, light 3 blocks from the torch %d, want 11", l)
+	}
+	if l := m.BlockLight(2, 5, 22); l != 0 {
+		t.Fatalf("Error, light above the roof %d, want 0", l)
+	}
+	if l := m.SkyLight(1, 1, 1); l != MaxLight {
+		t.Fatalf("Error, sky light under glass %d, want %d", l, MaxLight)
+	}
+	if l := m.SkyLight(2, 1, 1); l != MaxLight-1 {
+		t.Fatalf("Error, sky light next to the glass %d, want %d", l, MaxLight-1)
+	}
+	if l := m.SkyLight(22, 1, 22); l != 0 {
+		t.Fatalf("Error, sky light under the roof %d, want 0", l)
+	}
+
+	r := p.SpawnReport(SpawnOptions{})
+	spawnable := make(map[Vec3D]bool)
+	for _, s := range r.Surfaces {
+		spawnable[s] = true
+	}
+	for _, c := range []struct {
+		pos Vec3D
+		ok  bool
+	}{
+		{Vec3D{22, 0, 22}, true},
+		{Vec3D{2, 0, 22}, false},  // torch
+		{Vec3D{1, 0, 1}, false},   // window
+		{Vec3D{14, 4, 14}, false}, // sky
+		{Vec3D{22, 3, 22}, false}, // air
+	} {
+		if spawnable[c.pos] != c.ok {
+			t.Fatalf("Error, %v spawnable %t, want %t", c.pos, spawnable[c.pos], c.ok)
+		}
+	}
+	if r = p.SpawnReport(SpawnOptions{IgnoreSky: true}); !contains(r.Surfaces, Vec3D{14, 4, 14}) {
+		t.Fatalf("Error, the roof isn't spawnable at night")
+	}
+	o := r.Overlay("spawns")
+	if o.GetBlock(22, 1, 22).Name != "minecraft:red_carpet" || o.GetBlock(2, 1, 22).Name != air {
+		t.Fatalf("Error, overlay doesn't mark the surfaces")
+	}
+	if int(o.MetaData.TotalBlocks) != len(r.Surfaces) {
+		t.Fatalf("Error, %d markers, want %d", o.MetaData.TotalBlocks, len(r.Surfaces))
+	}
+}
+
+func contains(s []Vec3D, v Vec3D) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
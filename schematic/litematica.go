@@ -36,7 +36,7 @@ type Metadata struct {
 
 type RegionWithRawMessage struct {
 	BlockStatePalette []state
-	TileEntities      []CompoundTag
+	TileEntities      []nbt.RawMessage
 	Entities          []nbt.RawMessage
 	Position          Vec3D
 	Size              Vec3D
@@ -45,8 +45,8 @@ type RegionWithRawMessage struct {
 
 type Region struct {
 	BlockStatePalette []BlockState
-	TileEntities      []CompoundTag
-	Entities          []Entity
+	TileEntities      RawList
+	Entities          EntityList
 	Position          Vec3D
 	Size              Vec3D
 	BlockStates       []int64
@@ -97,16 +97,18 @@ func (l *Litematic) toProject() (*Project, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Project{
+	p := &Project{
 		MetaData:             l.Metadata,
 		MinecraftDataVersion: l.MinecraftDataVersion,
 		Version:              l.Version,
 		RegionName:           regName,
 		regionSize:           reg.Size,
 		palette:              newBlockStatePaletteWithData(reg.BlockStatePalette),
-		data:                 NewBitArray(bits.Len(uint(len(reg.BlockStatePalette)-1)), int(l.Metadata.TotalVolume), reg.BlockStates),
+		blocks:               &denseStorage{l.Metadata.EnclosingSize, NewBitArray(bits.Len(uint(len(reg.BlockStatePalette)-1)), int(l.Metadata.TotalVolume), reg.BlockStates)},
 		entity:               newEntityContainerWithData(reg.Entities),
-	}, nil
+	}
+	p.setTileEntities(reg.TileEntities)
+	return p, nil
 }
 
 func (l *Litematic) Encode(w io.Writer) error {
@@ -0,0 +1,216 @@
+package schematic
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"github.com/Tnze/go-mc/nbt"
+	"io"
+	"math"
+	"math/bits"
+)
+
+// LitematicHeader is what ReadLitematicaHeader reads of a litematic file:
+// everything but the blocks, tile entities and entities of the regions.
+type LitematicHeader struct {
+	Metadata             Metadata
+	MinecraftDataVersion int32
+	Version              int32
+	Regions              map[string]RegionHeader
+}
+
+type RegionHeader struct {
+	BlockStatePalette []BlockState
+	Position          Vec3D
+	Size              Vec3D
+}
+
+type regionHeaderWithRawMessage struct {
+	BlockStatePalette []state
+	Position          Vec3D
+	Size              Vec3D
+}
+
+// ReadLitematicaHeader reads the metadata, versions and the palette, position
+// and size of each region of a litematic file. The block states and entity
+// lists are skipped without being allocated, so it is cheap even for huge
+// files.
+func ReadLitematicaHeader(r io.Reader) (*LitematicHeader, error) {
+	var l struct {
+		Metadata             Metadata
+		MinecraftDataVersion int32
+		Version              int32
+		Regions              map[string]regionHeaderWithRawMessage
+	}
+	reader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	_, err = nbt.NewDecoder(bufio.NewReader(reader)).Decode(&l)
+	if err != nil {
+		return nil, err
+	}
+	h := &LitematicHeader{
+		Metadata:             l.Metadata,
+		MinecraftDataVersion: l.MinecraftDataVersion,
+		Version:              l.Version,
+		Regions:              make(map[string]RegionHeader, len(l.Regions)),
+	}
+	for name, reg := range l.Regions {
+		h.Regions[name] = RegionHeader{
+			BlockStatePalette: parseBlocks(reg.BlockStatePalette),
+			Position:          reg.Position,
+			Size:              reg.Size,
+		}
+	}
+	return h, nil
+}
+
+// ReadLitematicaRegions reads a litematic file one region at a time and calls
+// fn with each of them as a Project, so only one region is in memory at once.
+// The regions of files saved by Litematica may come before the metadata, so
+// the projects are named after their region and the header, whose regions
+// have no palette, is only returned once the whole file is read. Returning an
+// error from fn stops the reading, so does a malformed region.
+func ReadLitematicaRegions(r io.Reader, fn func(name string, p *Project) error) (*LitematicHeader, error) {
+	l := struct {
+		Metadata             Metadata
+		MinecraftDataVersion int32
+		Version              int32
+		Regions              regionReader
+	}{Regions: regionReader{fn: fn, regions: make(map[string]RegionHeader)}}
+	reader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	_, err = nbt.NewDecoder(bufio.NewReader(reader)).Decode(&l)
+	if l.Regions.err != nil {
+		// the error of fn, not the one nbt wraps it in
+		return nil, l.Regions.err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &LitematicHeader{
+		Metadata:             l.Metadata,
+		MinecraftDataVersion: l.MinecraftDataVersion,
+		Version:              l.Version,
+		Regions:              l.Regions.regions,
+	}, nil
+}
+
+// regionReader decodes the Regions compound entry by entry.
+type regionReader struct {
+	fn      func(name string, p *Project) error
+	regions map[string]RegionHeader
+	err     error
+}
+
+func (rr *regionReader) UnmarshalNBT(tagType byte, r nbt.DecoderReader) error {
+	if tagType != nbt.TagCompound {
+		return fmt.Errorf("regions must be a compound, not tag %#02x", tagType)
+	}
+	for {
+		t, name, err := readTagHeader(r)
+		if err != nil {
+			return err
+		}
+		if t == nbt.TagEnd {
+			return nil
+		}
+		if t != nbt.TagCompound {
+			return fmt.Errorf("region %q must be a compound, not tag %#02x", name, t)
+		}
+		// the tag header was read, give the decoder an unnamed one
+		var reg RegionWithRawMessage
+		if _, err := nbt.NewDecoder(&prefixReader{[]byte{nbt.TagCompound, 0, 0}, r}).Decode(&reg); err != nil {
+			return err
+		}
+		rr.regions[name] = RegionHeader{Position: reg.Position, Size: reg.Size}
+		p, err := regionProject(name, reg)
+		if err == nil {
+			err = rr.fn(name, p)
+		}
+		if err != nil {
+			rr.err = err
+			return err
+		}
+	}
+}
+
+// regionProject returns a region of a litematic file as a Project, regions
+// with negative sizes extend towards negative coordinates from their
+// position but store their blocks the same way. Regions that don't match their
+// palette and size are an error.
+func regionProject(name string, reg RegionWithRawMessage) (*Project, error) {
+	size := Vec3D{absInt32(reg.Size.X), absInt32(reg.Size.Y), absInt32(reg.Size.Z)}
+	if size.X < 0 || size.Y < 0 || size.Z < 0 {
+		return nil, fmt.Errorf("region %q: invalid size %v", name, reg.Size)
+	}
+	volume := int64(size.X) * int64(size.Y) * int64(size.Z)
+	if volume > math.MaxInt32 {
+		return nil, fmt.Errorf("region %q: size %v is too large", name, reg.Size)
+	}
+	if len(reg.BlockStatePalette) == 0 {
+		return nil, fmt.Errorf("region %q: empty palette", name)
+	}
+	bitsPerEntry := max(defaultBits, bits.Len(uint(len(reg.BlockStatePalette)-1)))
+	if n := (volume*int64(bitsPerEntry) + 63) / 64; int64(len(reg.BlockStates)) != n {
+		return nil, fmt.Errorf("region %q: %d longs of block states, want %d", name, len(reg.BlockStates), n)
+	}
+	palette, err := parseBlockStates(reg.BlockStatePalette)
+	if err != nil {
+		return nil, fmt.Errorf("region %q: %w", name, err)
+	}
+	p := NewProject(name, 0, 0, 0)
+	p.MetaData.EnclosingSize = size
+	p.MetaData.TotalVolume = int32(volume)
+	p.regionSize = reg.Size
+	p.palette = newBlockStatePaletteWithData(palette)
+	p.blocks = &denseStorage{size, NewBitArray(bitsPerEntry, int(volume), reg.BlockStates)}
+	p.entity = newEntityContainerWithData(parseEntities(reg.Entities))
+	p.setTileEntities(reg.TileEntities)
+	p.Compact()
+	return p, nil
+}
+
+func readTagHeader(r nbt.DecoderReader) (byte, string, error) {
+	t, err := r.ReadByte()
+	if err != nil || t == nbt.TagEnd {
+		return t, "", err
+	}
+	var n uint16
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return t, "", err
+	}
+	name := make([]byte, n)
+	_, err = io.ReadFull(r, name)
+	return t, string(name), err
+}
+
+// prefixReader reads prefix before r.
+type prefixReader struct {
+	prefix []byte
+	r      nbt.DecoderReader
+}
+
+func (p *prefixReader) Read(b []byte) (int, error) {
+	if len(p.prefix) == 0 {
+		return p.r.Read(b)
+	}
+	n := copy(b, p.prefix)
+	p.prefix = p.prefix[n:]
+	return n, nil
+}
+
+func (p *prefixReader) ReadByte() (byte, error) {
+	if len(p.prefix) == 0 {
+		return p.r.ReadByte()
+	}
+	b := p.prefix[0]
+	p.prefix = p.prefix[1:]
+	return b, nil
+}
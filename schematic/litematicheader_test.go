@@ -0,0 +1,92 @@
+package schematic
+
+import (
+	"bytes"
+	"errors"
+	"github.com/Tnze/go-mc/level/block"
+	"testing"
+)
+
+// twoRegions returns a litematic file with a region "a" of 4x3x2 and a region
+// "b" of -2x1x-3.
+func twoRegions(t *testing.T) []byte {
+	a := NewProject("a", 4, 3, 2)
+	a.SetBlock(3, 2, 1, block.Glowstone{})
+	b := NewProject("b", 2, 1, 3)
+	b.SetBlock(1, 0, 2, block.OakLog{Axis: block.Z})
+	b.SetBlock(0, 0, 0, block.Stone{})
+	l := a.Litematic()
+	rb := b.region()["b"]
+	rb.Size = Vec3D{-2, 1, -3}
+	rb.Position = Vec3D{10, 0, 10}
+	l.Regions["b"] = rb
+	l.Metadata.RegionCount = 2
+	var buf bytes.Buffer
+	if err := l.Encode(&buf); err != nil {
+		t.Fatalf("Error, encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadLitematicaHeader(t *testing.T) {
+	h, err := ReadLitematicaHeader(bytes.NewReader(twoRegions(t)))
+	if err != nil {
+		t.Fatalf("Error, read header: %v", err)
+	}
+	if h.Metadata.RegionCount != 2 || h.MinecraftDataVersion != int32(defaultMinecraftDataVersion) || len(h.Regions) != 2 {
+		t.Fatalf("Error, header: %+v", h)
+	}
+	b := h.Regions["b"]
+	if b.Size != (Vec3D{-2, 1, -3}) || b.Position != (Vec3D{10, 0, 10}) || len(b.BlockStatePalette) != 3 || b.BlockStatePalette[2].Name != "minecraft:stone" {
+		t.Fatalf("Error, region b: %+v", b)
+	}
+}
+
+func TestReadLitematicaRegions(t *testing.T) {
+	data := twoRegions(t)
+	seen := make(map[string]*Project)
+	h, err := ReadLitematicaRegions(bytes.NewReader(data), func(name string, p *Project) error {
+		seen[name] = p
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error, read regions: %v", err)
+	}
+	if len(h.Regions) != 2 || h.Metadata.Name != "a" || len(seen) != 2 {
+		t.Fatalf("Error, header %+v, regions %v", h, seen)
+	}
+	if p := seen["a"]; p.GetBlock(3, 2, 1).Name != "minecraft:glowstone" || p.MetaData.TotalBlocks != 1 {
+		t.Fatalf("Error, region a is wrong")
+	}
+	if p := seen["b"]; p.Size() != (Vec3D{2, 1, 3}) || p.GetBlock(1, 0, 2).Name != "minecraft:oak_log" || p.MetaData.TotalBlocks != 2 {
+		t.Fatalf("Error, region b is wrong")
+	}
+
+	stop := errors.New("stop")
+	_, err = ReadLitematicaRegions(bytes.NewReader(data), func(name string, p *Project) error {
+		return stop
+	})
+	if err != stop {
+		t.Fatalf("Error, got %v, want the error of fn", err)
+	}
+}
+
+func TestReadLitematicaRegionsMalformed(t *testing.T) {
+	for _, broken := range []func(r *Region){
+		func(r *Region) { r.BlockStates = r.BlockStates[:1] },
+		func(r *Region) { r.BlockStatePalette = nil },
+		func(r *Region) { r.Size = Vec3D{-2147483648, 1, 1} },
+	} {
+		l := NewProject("a", 40, 3, 2).Litematic()
+		r := l.Regions["a"]
+		broken(&r)
+		l.Regions["a"] = r
+		var buf bytes.Buffer
+		if err := l.Encode(&buf); err != nil {
+			t.Fatalf("Error, encode: %v", err)
+		}
+		if _, err := ReadLitematicaRegions(&buf, func(name string, p *Project) error { return nil }); err == nil {
+			t.Fatalf("Error, malformed region read without error")
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package schematic
+
+import (
+	"github.com/Tnze/go-mc/level/block"
+	"strings"
+)
+
+// Mask selects the positions an operation applies to.
+type Mask interface {
+	Test(p *Project, x, y, z int) bool
+}
+
+// MaskFunc adapts a function to the Mask interface.
+type MaskFunc func(p *Project, x, y, z int) bool
+
+func (f MaskFunc) Test(p *Project, x, y, z int) bool { return f(p, x, y, z) }
+
+// stateMask tests only the block at the position, most masks are of this kind.
+func stateMask(f func(s BlockState) bool) Mask {
+	return MaskFunc(func(p *Project, x, y, z int) bool {
+		return f(p.GetBlock(x, y, z))
+	})
+}
+
+// States matches the exact block states, properties included.
+func States(states ...BlockState) Mask {
+	set := make(map[BlockState]bool, len(states))
+	for _, s := range states {
+		set[s] = true
+	}
+	return stateMask(func(s BlockState) bool { return set[s] })
+}
+
+// Exact matches the exact block states of the given blocks.
+func Exact(blocks ...block.Block) Mask {
+	states := make([]BlockState, len(blocks))
+	for i, b := range blocks {
+		states[i] = NewBlockState(b)
+	}
+	return States(states...)
+}
+
+// Names matches blocks by ID whatever their properties, e.g. "oak_stairs" or
+// "minecraft:oak_stairs".
+func Names(names ...string) Mask {
+	set := make(map[string]bool, len(names))
+	for _, n := range names {
+		set[namespaced(n)] = true
+	}
+	return stateMask(func(s BlockState) bool { return set[s.Name] })
+}
+
+// Property matches blocks having the property with the given value, like
+// facing=north. Blocks without the property don't match.
+func Property(name, value string) Mask {
+	return stateMask(func(s BlockState) bool {
+		return s.Properties != nil && property(s.Properties, name) == value
+	})
+}
+
+// BlockTag matches the blocks of a block tag from BlockTags, e.g. "#minecraft:logs" or "logs".
+func BlockTag(name string) Mask {
+	name = namespaced(strings.TrimPrefix(name, "#"))
+	return stateMask(func(s BlockState) bool {
+		return BlockTags[name][s.Name]
+	})
+}
+
+// Existing matches every block that isn't air.
+func Existing() Mask {
+	return stateMask(func(s BlockState) bool {
+		return s.Properties != nil && !block.IsAirBlock(s.Properties)
+	})
+}
+
+// Offset tests m at the position moved by dx, dy, dz, e.g. Offset(0, 1, 0, m)
+// matches when the block above matches m. Positions outside the project are air.
+func Offset(dx, dy, dz int, m Mask) Mask {
+	return MaskFunc(func(p *Project, x, y, z int) bool {
+		return m.Test(p, x+dx, y+dy, z+dz)
+	})
+}
+
+// And matches when all the masks match.
+func And(masks ...Mask) Mask {
+	return MaskFunc(func(p *Project, x, y, z int) bool {
+		for _, m := range masks {
+			if !m.Test(p, x, y, z) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Or matches when any of the masks matches.
+func Or(masks ...Mask) Mask {
+	return MaskFunc(func(p *Project, x, y, z int) bool {
+		for _, m := range masks {
+			if m.Test(p, x, y, z) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Not matches when m doesn't.
+func Not(m Mask) Mask {
+	return MaskFunc(func(p *Project, x, y, z int) bool {
+		return !m.Test(p, x, y, z)
+	})
+}
+
+func namespaced(name string) string {
+	if strings.Contains(name, ":") {
+		return name
+	}
+	return "minecraft:" + name
+}
+
+// BlockTags maps tag names to the IDs of their blocks. It holds the common
+// vanilla tags derived from block IDs, more can be added before use.
+var BlockTags = map[string]map[string]bool{}
+
+func init() {
+	suffixTags := map[string][]string{
+		"minecraft:logs":            {"_log", "_wood", "_stem", "_hyphae"},
+		"minecraft:planks":          {"_planks"},
+		"minecraft:wool":            {"_wool"},
+		"minecraft:stairs":          {"_stairs"},
+		"minecraft:slabs":           {"_slab"},
+		"minecraft:walls":           {"_wall"},
+		"minecraft:fences":          {"_fence"},
+		"minecraft:fence_gates":     {"_fence_gate"},
+		"minecraft:leaves":          {"_leaves"},
+		"minecraft:doors":           {"_door"},
+		"minecraft:trapdoors":       {"_trapdoor"},
+		"minecraft:buttons":         {"_button"},
+		"minecraft:pressure_plates": {"_pressure_plate"},
+		"minecraft:signs":           {"_sign"},
+		"minecraft:beds":            {"_bed"},
+		"minecraft:banners":         {"_banner"},
+		"minecraft:saplings":        {"_sapling"},
+		"minecraft:shulker_boxes":   {"shulker_box"},
+		"minecraft:candles":         {"candle"},
+		"minecraft:rails":           {"rail"},
+		"minecraft:flower_pots":     {"flower_pot"},
+		"minecraft:terracotta":      {"terracotta"},
+		"minecraft:concrete":        {"_concrete"},
+		"minecraft:concrete_powder": {"_concrete_powder"},
+		"minecraft:wool_carpets":    {"_carpet"},
+		"minecraft:glass":           {"glass"},
+		"minecraft:glass_panes":     {"glass_pane"},
+		"minecraft:ice":             {"ice"},
+	}
+	excluded := map[string]bool{
+		"minecraft:moss_carpet": true, "minecraft:mushroom_stem": true,
+		"minecraft:melon_stem": true, "minecraft:pumpkin_stem": true,
+		"minecraft:attached_melon_stem": true, "minecraft:attached_pumpkin_stem": true,
+	}
+	for id := range block.FromID {
+		if excluded[id] {
+			continue
+		}
+		for tag, suffixes := range suffixTags {
+			for _, s := range suffixes {
+				if strings.HasSuffix(id, s) && !strings.HasSuffix(id, "glazed_terracotta") {
+					if BlockTags[tag] == nil {
+						BlockTags[tag] = make(map[string]bool)
+					}
+					BlockTags[tag][id] = true
+				}
+			}
+		}
+	}
+	BlockTags["minecraft:sand"] = map[string]bool{"minecraft:sand": true, "minecraft:red_sand": true, "minecraft:suspicious_sand": true}
+	BlockTags["minecraft:dirt"] = map[string]bool{
+		"minecraft:dirt": true, "minecraft:grass_block": true, "minecraft:podzol": true, "minecraft:coarse_dirt": true,
+		"minecraft:mycelium": true, "minecraft:rooted_dirt": true, "minecraft:moss_block": true, "minecraft:mud": true,
+		"minecraft:muddy_mangrove_roots": true,
+	}
+	BlockTags["minecraft:base_stone_overworld"] = map[string]bool{
+		"minecraft:stone": true, "minecraft:granite": true, "minecraft:diorite": true, "minecraft:andesite": true,
+		"minecraft:tuff": true, "minecraft:deepslate": true,
+	}
+}
@@ -0,0 +1,45 @@
+package schematic
+
+import (
+	"fmt"
+	"github.com/Tnze/go-mc/level/block"
+)
+
+// ChangeMaterials replaces the states in table with their values and returns
+// the number of blocks changed. The palette entries are merged when a new
+// state is already used, and TotalBlocks is kept right when blocks are
+// changed to or from air.
+func (p *Project) ChangeMaterials(table map[BlockState]BlockState) int {
+	return p.mapStates(func(s BlockState) BlockState {
+		if n, ok := table[s]; ok {
+			return n
+		}
+		return s
+	})
+}
+
+// ChangeBlockType replaces every block of ID from with a block of ID to, e.g.
+// "oak_stairs" with "spruce_stairs", keeping the properties both blocks have
+// such as facing, half and shape. It returns the number of blocks changed.
+func (p *Project) ChangeBlockType(from, to string) (int, error) {
+	return p.ChangeBlockTypes(map[string]string{from: to})
+}
+
+// ChangeBlockTypes is ChangeBlockType for many block IDs at once.
+func (p *Project) ChangeBlockTypes(table map[string]string) (int, error) {
+	types := make(map[string]block.Block, len(table))
+	for from, to := range table {
+		b, ok := block.FromID[namespaced(to)]
+		if !ok {
+			return 0, fmt.Errorf("unknown block: %s", to)
+		}
+		types[namespaced(from)] = b
+	}
+	return p.mapStates(func(s BlockState) BlockState {
+		b, ok := types[s.Name]
+		if !ok {
+			return s
+		}
+		return NewBlockState(withProperties(b, properties(s.Properties)))
+	}), nil
+}
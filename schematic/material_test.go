@@ -0,0 +1,53 @@
+package schematic
+
+import (
+	"github.com/Tnze/go-mc/level/block"
+	"testing"
+)
+
+func TestChangeMaterial(t *testing.T) {
+	p := NewProject("material", 4, 1, 1)
+	p.SetBlock(0, 0, 0, block.Dirt{})
+	p.SetBlock(1, 0, 0, block.Stone{})
+	p.SetBlock(2, 0, 0, block.Dirt{})
+
+	// stone is already in the palette, the entries must be merged
+	p.ChangeMaterial(block.Dirt{}, block.Stone{})
+	p.SetBlock(3, 0, 0, block.Stone{})
+	if len(p.Palette()) != 2 || p.MetaData.TotalBlocks != 4 {
+		t.Fatalf("Error, palette %v, total blocks %d", p.Palette(), p.MetaData.TotalBlocks)
+	}
+	n := p.ChangeMaterials(map[BlockState]BlockState{NewBlockState(block.Stone{}): Air})
+	if n != 4 || p.MetaData.TotalBlocks != 0 || p.GetBlock(1, 0, 0) != Air {
+		t.Fatalf("Error, changed %d blocks, total blocks %d", n, p.MetaData.TotalBlocks)
+	}
+	p.ChangeMaterial(block.Air{}, block.Glass{})
+	if p.MetaData.TotalBlocks != 4 || p.GetBlock(3, 0, 0).Name != "minecraft:glass" {
+		t.Fatalf("Error, total blocks %d after air to glass", p.MetaData.TotalBlocks)
+	}
+}
+
+func TestChangeBlockType(t *testing.T) {
+	p := NewProject("material", 3, 1, 1)
+	p.SetBlock(0, 0, 0, block.OakStairs{Facing: block.East, Half: block.Top, Shape: block.StairsShapeInnerLeft})
+	p.SetBlock(1, 0, 0, block.OakStairs{Facing: block.West})
+	p.SetBlock(2, 0, 0, block.OakLog{Axis: block.X})
+	n, err := p.ChangeBlockTypes(map[string]string{"oak_stairs": "spruce_stairs", "minecraft:oak_log": "stripped_oak_log"})
+	if err != nil || n != 3 {
+		t.Fatalf("Error, changed %d blocks: %v", n, err)
+	}
+	want := block.SpruceStairs{Facing: block.East, Half: block.Top, Shape: block.StairsShapeInnerLeft}
+	if p.GetBlock(0, 0, 0) != NewBlockState(want) {
+		t.Fatalf("Error, got %v, want %v", p.GetBlock(0, 0, 0), want)
+	}
+	if p.GetBlock(2, 0, 0) != NewBlockState(block.StrippedOakLog{Axis: block.X}) {
+		t.Fatalf("Error, got %v", p.GetBlock(2, 0, 0))
+	}
+	// properties the new block doesn't have are dropped
+	if _, err := p.ChangeBlockType("spruce_stairs", "stone"); err != nil || p.GetBlock(1, 0, 0) != NewBlockState(block.Stone{}) {
+		t.Fatalf("Error, got %v: %v", p.GetBlock(1, 0, 0), err)
+	}
+	if _, err := p.ChangeBlockType("stone", "not_a_block"); err == nil {
+		t.Fatalf("Error, unknown block accepted")
+	}
+}
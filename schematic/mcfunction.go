@@ -0,0 +1,196 @@
+package schematic
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	//MaxFunctionCommands is the number of commands a function runs by
+	//default, the maxCommandChainLength game rule
+	MaxFunctionCommands = 65536
+
+	//MaxFillVolume is the largest number of blocks a /fill command sets
+	MaxFillVolume = 32768
+)
+
+// FunctionOptions configure the commands that build a project.
+type FunctionOptions struct {
+	//Origin is the position of the project's corner in the world, the
+	//commands use coordinates relative to where they run if it is nil
+	Origin *Vec3D
+
+	//IncludeAir fills the air of the project too, clearing what is in the way
+	IncludeAir bool
+
+	//MaxCommands per function, MaxFunctionCommands if 0 or larger
+	MaxCommands int
+}
+
+// BlockString returns the block state in command syntax, e.g.
+// minecraft:oak_stairs[facing=east,half=bottom,shape=straight,waterlogged=false].
+func BlockString(s BlockState) string {
+	props := properties(s.Properties)
+	if len(props) == 0 {
+		return s.Name
+	}
+	names := make([]string, 0, len(props))
+	for n := range props {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteString(s.Name)
+	for i, n := range names {
+		if i == 0 {
+			b.WriteByte('[')
+		} else {
+			b.WriteByte(',')
+		}
+		b.WriteString(n + "=" + props[n])
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// Commands returns /fill and /setblock commands that build the project. Runs
+// of the same block are merged greedily into boxes along x, then z, then y,
+// each at most MaxFillVolume blocks, and the commands go from the bottom up.
+// Blocks with a block entity get their own /setblock with its NBT.
+//
+// Only the blocks that are not air are walked unless opt.IncludeAir is set.
+// Besides the commands, the memory used is that of the block entities and of
+// the positions of merged boxes the walk hasn't reached yet, so large sparse
+// projects don't cost their volume.
+func (p *Project) Commands(opt FunctionOptions) []string {
+	size := p.Size()
+	sx, sy, sz := int(size.X), int(size.Y), int(size.Z)
+	p.mu.RLock()
+	tiles := make(map[Vec3D]string, len(p.blockEntities))
+	for v, m := range p.blockEntities {
+		if p.palette.value(p.blocks.get(int(v.X), int(v.Y), int(v.Z))).Name != air {
+			tiles[v] = m.String()
+		}
+	}
+	p.mu.RUnlock()
+
+	pos := func(x, y, z int) string {
+		if opt.Origin == nil {
+			return fmt.Sprintf("~%d ~%d ~%d", x, y, z)
+		}
+		return fmt.Sprintf("%d %d %d", int(opt.Origin.X)+x, int(opt.Origin.Y)+y, int(opt.Origin.Z)+z)
+	}
+	states := make(map[BlockState]string)
+	state := func(s BlockState) string {
+		if _, ok := states[s]; !ok {
+			states[s] = BlockString(s)
+		}
+		return states[s]
+	}
+
+	var commands []string
+	// done holds the blocks of merged boxes ahead of the walk, they are
+	// removed when the walk gets to them
+	done := make(map[Vec3D]bool)
+	free := func(x, y, z int, s BlockState) bool {
+		v := Vec3D{int32(x), int32(y), int32(z)}
+		if _, ok := tiles[v]; ok || done[v] {
+			return false
+		}
+		return p.GetBlock(x, y, z) == s
+	}
+	p.each(p.Bounds(), !opt.IncludeAir, func(x, y, z int, s BlockState) bool {
+		v := Vec3D{int32(x), int32(y), int32(z)}
+		if done[v] {
+			delete(done, v)
+			return true
+		}
+		if nbt, ok := tiles[v]; ok {
+			commands = append(commands, "setblock "+pos(x, y, z)+" "+state(s)+nbt)
+			return true
+		}
+
+		x1 := x
+		for x1+1 < sx && x1+2-x <= MaxFillVolume && free(x1+1, y, z, s) {
+			x1++
+		}
+		z1 := z
+	growZ:
+		for z1+1 < sz && (x1-x+1)*(z1+2-z) <= MaxFillVolume {
+			for bx := x; bx <= x1; bx++ {
+				if !free(bx, y, z1+1, s) {
+					break growZ
+				}
+			}
+			z1++
+		}
+		y1 := y
+	growY:
+		for y1+1 < sy && (x1-x+1)*(z1-z+1)*(y1+2-y) <= MaxFillVolume {
+			for bz := z; bz <= z1; bz++ {
+				for bx := x; bx <= x1; bx++ {
+					if !free(bx, y1+1, bz, s) {
+						break growY
+					}
+				}
+			}
+			y1++
+		}
+
+		for by := y; by <= y1; by++ {
+			for bz := z; bz <= z1; bz++ {
+				for bx := x; bx <= x1; bx++ {
+					if bx != x || by != y || bz != z {
+						done[Vec3D{int32(bx), int32(by), int32(bz)}] = true
+					}
+				}
+			}
+		}
+		if x == x1 && y == y1 && z == z1 {
+			commands = append(commands, "setblock "+pos(x, y, z)+" "+state(s))
+		} else {
+			commands = append(commands, "fill "+pos(x, y, z)+" "+pos(x1, y1, z1)+" "+state(s))
+		}
+		return true
+	})
+	return commands
+}
+
+// MCFunctions returns the commands of the project split into functions of at
+// most opt.MaxCommands commands, one per line.
+func (p *Project) MCFunctions(opt FunctionOptions) []string {
+	n := opt.MaxCommands
+	if n <= 0 || n > MaxFunctionCommands {
+		n = MaxFunctionCommands
+	}
+	commands := p.Commands(opt)
+	var functions []string
+	for len(commands) > 0 {
+		c := commands[:minInt(n, len(commands))]
+		commands = commands[len(c):]
+		functions = append(functions, strings.Join(c, "\n")+"\n")
+	}
+	return functions
+}
+
+// WriteMCFunctions writes the functions of the project to dir as
+// <name>.mcfunction, or <name>_0.mcfunction, <name>_1.mcfunction and so on
+// when there is more than one, and returns their paths.
+func (p *Project) WriteMCFunctions(dir, name string, opt FunctionOptions) ([]string, error) {
+	functions := p.MCFunctions(opt)
+	var paths []string
+	for i, f := range functions {
+		path := filepath.Join(dir, name+".mcfunction")
+		if len(functions) > 1 {
+			path = filepath.Join(dir, fmt.Sprintf("%s_%d.mcfunction", name, i))
+		}
+		if err := os.WriteFile(path, []byte(f), 0o644); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
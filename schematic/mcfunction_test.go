@@ -0,0 +1,101 @@
+package schematic
+
+import (
+	"fmt"
+	"github.com/Tnze/go-mc/level/block"
+	"strings"
+	"testing"
+)
+
+// runCommands applies commands written with relative coordinates to a
+// project of the given size.
+func runCommands(t *testing.T, commands []string, size Vec3D, states map[string]BlockState) *Project {
+	p := NewProject("run", int(size.X), int(size.Y), int(size.Z))
+	for _, c := range commands {
+		f := strings.Fields(strings.ReplaceAll(c, "~", ""))
+		var n [6]int
+		coords := 3
+		if f[0] == "fill" {
+			coords = 6
+		}
+		for i := 0; i < coords; i++ {
+			fmt.Sscan(f[1+i], &n[i])
+		}
+		if coords == 3 {
+			n[3], n[4], n[5] = n[0], n[1], n[2]
+		}
+		if v := (n[3] - n[0] + 1) * (n[4] - n[1] + 1) * (n[5] - n[2] + 1); v > MaxFillVolume {
+			t.Fatalf("Error, %q sets %d blocks", c, v)
+		}
+		state := f[1+coords]
+		if i := strings.IndexByte(state, '{'); i >= 0 {
+			state = state[:i]
+		}
+		s, ok := states[state]
+		if !ok {
+			t.Fatalf("Error, unknown state in %q", c)
+		}
+		p.Fill(NewBox(n[0], n[1], n[2], n[3], n[4], n[5]), Single(s.Properties), nil)
+	}
+	return p
+}
+
+func TestCommands(t *testing.T) {
+	p := NewProject("wall", 40, 30, 40)
+	p.Fill(p.Bounds(), Single(block.Stone{}), nil)
+	p.Fill(NewBox(3, 3, 3, 12, 12, 12), Single(block.Air{}), nil)
+	p.SetBlock(5, 3, 5, block.OakStairs{Facing: block.East, Half: block.Top})
+	p.SetBlock(7, 3, 7, block.Chest{Facing: block.North})
+	_ = p.SetBlockEntity(7, 3, 7, testChest{ID: "minecraft:chest", Items: []testItem{{0, "minecraft:stick", 1}}})
+
+	commands := p.Commands(FunctionOptions{})
+	if len(commands) > 20 {
+		t.Fatalf("Error, %d commands for a hollow cube", len(commands))
+	}
+	states := make(map[string]BlockState)
+	for _, s := range p.Palette() {
+		states[BlockString(s)] = s
+	}
+	if _, ok := states["minecraft:oak_stairs[facing=east,half=top,shape=straight,waterlogged=false]"]; !ok {
+		t.Fatalf("Error, block strings %v", states)
+	}
+	var chest string
+	for _, c := range commands {
+		if strings.HasPrefix(c, "setblock ~7 ~3 ~7 minecraft:chest[") {
+			chest = c
+		}
+	}
+	if !strings.HasSuffix(chest, `{id:"minecraft:chest",Items:[{Slot:0B,id:"minecraft:stick",Count:1B}]}`) {
+		t.Fatalf("Error, chest command %q", chest)
+	}
+	q := runCommands(t, commands, p.Size(), states)
+	p.ForEachBlock(func(x, y, z int, s BlockState) {
+		if q.GetBlock(x, y, z) != s {
+			t.Fatalf("Error, block at %d, %d, %d: %v, want %v", x, y, z, q.GetBlock(x, y, z), s)
+		}
+	})
+
+	functions := p.MCFunctions(FunctionOptions{MaxCommands: 4, Origin: &Vec3D{100, 64, -20}})
+	if len(functions) != (len(commands)+3)/4 {
+		t.Fatalf("Error, %d functions for %d commands", len(functions), len(commands))
+	}
+	if !strings.HasPrefix(functions[0], "fill 100 64 -20 ") {
+		t.Fatalf("Error, function with an absolute origin:\n%s", functions[0])
+	}
+
+	air := p.Commands(FunctionOptions{IncludeAir: true})
+	if len(air) <= len(commands) || !strings.Contains(strings.Join(air, "\n"), "minecraft:air") {
+		t.Fatalf("Error, air isn't filled")
+	}
+}
+
+func TestCommandsSparse(t *testing.T) {
+	// the volume is too large to keep anything per block
+	p := NewSparseProject("sparse", 1024, 64, 1024)
+	p.Fill(NewBox(500, 10, 500, 503, 11, 501), Single(block.Stone{}), nil)
+	p.SetBlock(1000, 60, 3, block.Dirt{})
+	commands := p.Commands(FunctionOptions{})
+	if len(commands) != 2 || commands[0] != "fill ~500 ~10 ~500 ~503 ~11 ~501 minecraft:stone" || commands[1] != "setblock ~1000 ~60 ~3 minecraft:dirt" {
+		t.Fatalf("Error, commands %v", commands)
+	}
+}
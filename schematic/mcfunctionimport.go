@@ -0,0 +1,429 @@
+package schematic
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/Tnze/go-mc/level/block"
+	"github.com/Tnze/go-mc/nbt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MaxImportSize is the largest size along each axis of the project
+// ImportMCFunction builds, its volume must also fit in an int32 as Litematica
+// stores it.
+const MaxImportSize = 4096
+
+// FunctionReport tells what ImportMCFunction did.
+type FunctionReport struct {
+	//Origin World position of the project's corner
+	Origin Vec3D
+
+	//Commands Number of commands run
+	Commands int
+
+	//Unsupported Commands that were skipped
+	Unsupported []UnsupportedCommand
+}
+
+// UnsupportedCommand is a command ImportMCFunction couldn't run.
+type UnsupportedCommand struct {
+	Line    int
+	Command string
+	Reason  string
+}
+
+// command is a parsed setblock, fill or clone in world coordinates.
+type command struct {
+	name  string
+	box   Box
+	dest  Vec3D
+	state BlockState
+	nbt   *nbt.RawMessage
+	mode  string
+
+	//filter of fill replace and clone filtered, nil for all blocks
+	filter Mask
+
+	//move clears the source of a clone
+	move bool
+}
+
+// ImportMCFunction runs the setblock, fill and clone commands of an
+// mcfunction file and returns the blocks they place as a project. Relative
+// coordinates (~) are resolved from origin. The project grows to hold every
+// block the commands set or clone, Report.Origin is the world position of its
+// corner. Files spanning more than MaxImportSize are an error. Other
+// commands, commands that can't be parsed and fill or clone commands of more
+// than MaxFillVolume blocks, which the game refuses, are skipped and listed in
+// the report.
+func ImportMCFunction(name string, r io.Reader, origin Vec3D) (*Project, *FunctionReport, error) {
+	report := &FunctionReport{}
+	var commands []command
+	bounds := Box{Min: Vec3D{math.MaxInt32, math.MaxInt32, math.MaxInt32}, Max: Vec3D{math.MinInt32, math.MinInt32, math.MinInt32}}
+	grow := func(b Box) {
+		bounds.Min = Vec3D{min32(bounds.Min.X, b.Min.X), min32(bounds.Min.Y, b.Min.Y), min32(bounds.Min.Z, b.Min.Z)}
+		bounds.Max = Vec3D{max32(bounds.Max.X, b.Max.X), max32(bounds.Max.Y, b.Max.Y), max32(bounds.Max.Z, b.Max.Z)}
+	}
+
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<24)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimPrefix(strings.TrimSpace(s.Text()), "/")
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		c, err := parseCommand(text, origin)
+		if err != nil {
+			report.Unsupported = append(report.Unsupported, UnsupportedCommand{line, text, err.Error()})
+			continue
+		}
+		// the source of a clone is in the project too, it is read and
+		// cleared by move
+		grow(c.box)
+		if c.name == "clone" {
+			grow(Box{c.dest, Vec3D{c.dest.X + c.box.Max.X - c.box.Min.X, c.dest.Y + c.box.Max.Y - c.box.Min.Y, c.dest.Z + c.box.Max.Z - c.box.Min.Z}})
+		}
+		commands = append(commands, c)
+	}
+	if err := s.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if len(commands) == 0 {
+		return NewSparseProject(name, 0, 0, 0), report, nil
+	}
+	size := [3]int64{
+		int64(bounds.Max.X) - int64(bounds.Min.X) + 1, int64(bounds.Max.Y) - int64(bounds.Min.Y) + 1, int64(bounds.Max.Z) - int64(bounds.Min.Z) + 1,
+	}
+	if size[0] > MaxImportSize || size[1] > MaxImportSize || size[2] > MaxImportSize || size[0]*size[1]*size[2] > math.MaxInt32 {
+		return nil, nil, fmt.Errorf("the commands span %dx%dx%d blocks, more than %d along an axis or %d in all", size[0], size[1], size[2], MaxImportSize, math.MaxInt32)
+	}
+	report.Origin = bounds.Min
+	p := NewSparseProject(name, int(size[0]), int(size[1]), int(size[2]))
+	for _, c := range commands {
+		p.run(c, bounds.Min)
+		report.Commands++
+	}
+	return p, report, nil
+}
+
+// run runs the command c on p, whose corner is at o in the world.
+func (p *Project) run(c command, o Vec3D) {
+	box := Box{Vec3D{c.box.Min.X - o.X, c.box.Min.Y - o.Y, c.box.Min.Z - o.Z}, Vec3D{c.box.Max.X - o.X, c.box.Max.Y - o.Y, c.box.Max.Z - o.Z}}
+	set := func(x, y, z int, s BlockState, m *nbt.RawMessage) {
+		p.SetBlock(x, y, z, s.Properties)
+		p.RemoveBlockEntity(x, y, z)
+		if m != nil {
+			_ = p.SetBlockEntity(x, y, z, *m)
+		}
+	}
+	isAir := func(x, y, z int) bool { return p.GetBlock(x, y, z).Name == air }
+
+	switch c.name {
+	case "setblock":
+		x, y, z := int(box.Min.X), int(box.Min.Y), int(box.Min.Z)
+		if c.mode != "keep" || isAir(x, y, z) {
+			set(x, y, z, c.state, c.nbt)
+		}
+	case "fill":
+		for y := int(box.Min.Y); y <= int(box.Max.Y); y++ {
+			for z := int(box.Min.Z); z <= int(box.Max.Z); z++ {
+				for x := int(box.Min.X); x <= int(box.Max.X); x++ {
+					edge := x == int(box.Min.X) || x == int(box.Max.X) || y == int(box.Min.Y) || y == int(box.Max.Y) || z == int(box.Min.Z) || z == int(box.Max.Z)
+					switch {
+					case c.mode == "keep" && !isAir(x, y, z),
+						c.mode == "outline" && !edge,
+						c.filter != nil && !c.filter.Test(p, x, y, z):
+					case c.mode == "hollow" && !edge:
+						set(x, y, z, Air, nil)
+					default:
+						set(x, y, z, c.state, c.nbt)
+					}
+				}
+			}
+		}
+	case "clone":
+		// the source is read before anything is written, so it may overlap
+		// the destination
+		type copied struct {
+			x, y, z int
+			s       BlockState
+			m       *nbt.RawMessage
+		}
+		var blocks []copied
+		for y := int(box.Min.Y); y <= int(box.Max.Y); y++ {
+			for z := int(box.Min.Z); z <= int(box.Max.Z); z++ {
+				for x := int(box.Min.X); x <= int(box.Max.X); x++ {
+					b := copied{x: x, y: y, z: z, s: p.GetBlock(x, y, z)}
+					if (c.mode == "masked" && b.s.Name == air) || (c.filter != nil && !c.filter.Test(p, x, y, z)) {
+						continue
+					}
+					if m, ok := p.BlockEntity(x, y, z); ok {
+						b.m = &m
+					}
+					blocks = append(blocks, b)
+				}
+			}
+		}
+		if c.move {
+			for _, b := range blocks {
+				set(b.x, b.y, b.z, Air, nil)
+			}
+		}
+		dx, dy, dz := int(c.dest.X-c.box.Min.X), int(c.dest.Y-c.box.Min.Y), int(c.dest.Z-c.box.Min.Z)
+		for _, b := range blocks {
+			set(b.x+dx, b.y+dy, b.z+dz, b.s, b.m)
+		}
+	}
+}
+
+// parseCommand parses a setblock, fill or clone command.
+func parseCommand(text string, origin Vec3D) (command, error) {
+	args := commandArgs(text)
+	c := command{name: args[0]}
+	switch c.name {
+	case "setblock":
+		if len(args) < 5 || len(args) > 6 {
+			return c, fmt.Errorf("setblock takes a position, a block and a mode")
+		}
+		pos, err := parsePos(args[1:4], origin)
+		if err != nil {
+			return c, err
+		}
+		c.box = Box{pos, pos}
+		if c.state, c.nbt, err = parseBlock(args[4]); err != nil {
+			return c, err
+		}
+		if len(args) == 6 {
+			c.mode = args[5]
+			if !oneOf(c.mode, []string{"destroy", "keep", "replace"}) {
+				return c, fmt.Errorf("unknown setblock mode %q", c.mode)
+			}
+		}
+	case "fill":
+		if len(args) < 8 {
+			return c, fmt.Errorf("fill takes two positions, a block and a mode")
+		}
+		from, err := parsePos(args[1:4], origin)
+		if err != nil {
+			return c, err
+		}
+		to, err := parsePos(args[4:7], origin)
+		if err != nil {
+			return c, err
+		}
+		c.box = NewBox(int(from.X), int(from.Y), int(from.Z), int(to.X), int(to.Y), int(to.Z))
+		if c.state, c.nbt, err = parseBlock(args[7]); err != nil {
+			return c, err
+		}
+		if len(args) > 8 {
+			c.mode = args[8]
+			if !oneOf(c.mode, []string{"destroy", "hollow", "keep", "outline", "replace"}) {
+				return c, fmt.Errorf("unknown fill mode %q", c.mode)
+			}
+		}
+		if c.mode == "replace" && len(args) == 10 {
+			if c.filter, err = parseFilter(args[9]); err != nil {
+				return c, err
+			}
+		} else if len(args) > 9 {
+			return c, fmt.Errorf("too many arguments")
+		}
+	case "clone":
+		if len(args) < 10 {
+			return c, fmt.Errorf("clone takes three positions")
+		}
+		var pos [3]Vec3D
+		for i := range pos {
+			var err error
+			if pos[i], err = parsePos(args[1+3*i:4+3*i], origin); err != nil {
+				return c, err
+			}
+		}
+		c.box = NewBox(int(pos[0].X), int(pos[0].Y), int(pos[0].Z), int(pos[1].X), int(pos[1].Y), int(pos[1].Z))
+		c.dest = pos[2]
+		rest := args[10:]
+		if len(rest) > 0 {
+			c.mode = rest[0]
+			rest = rest[1:]
+			switch c.mode {
+			case "replace", "masked":
+			case "filtered":
+				if len(rest) == 0 {
+					return c, fmt.Errorf("clone filtered needs a block")
+				}
+				var err error
+				if c.filter, err = parseFilter(rest[0]); err != nil {
+					return c, err
+				}
+				rest = rest[1:]
+			default:
+				return c, fmt.Errorf("unknown clone mode %q", c.mode)
+			}
+		}
+		if len(rest) > 0 {
+			if !oneOf(rest[0], []string{"force", "move", "normal"}) || len(rest) > 1 {
+				return c, fmt.Errorf("unknown clone mode %q", strings.Join(rest, " "))
+			}
+			c.move = rest[0] == "move"
+		}
+	default:
+		return c, fmt.Errorf("unsupported command %s", c.name)
+	}
+	if c.name != "setblock" && tooLarge(c.box) {
+		return c, fmt.Errorf("%s of more than %d blocks", c.name, MaxFillVolume)
+	}
+	return c, nil
+}
+
+// tooLarge reports whether b holds more blocks than a fill or clone may
+// change in game.
+func tooLarge(b Box) bool {
+	v := int64(1)
+	for _, d := range []int64{
+		int64(b.Max.X) - int64(b.Min.X) + 1, int64(b.Max.Y) - int64(b.Min.Y) + 1, int64(b.Max.Z) - int64(b.Min.Z) + 1,
+	} {
+		if v *= d; v > MaxFillVolume {
+			return true
+		}
+	}
+	return false
+}
+
+// commandArgs splits a command at the spaces outside brackets, braces and
+// quotes.
+func commandArgs(text string) []string {
+	var args []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		case c == ' ' && depth == 0:
+			if i > start {
+				args = append(args, text[start:i])
+			}
+			start = i + 1
+		}
+	}
+	if start < len(text) {
+		args = append(args, text[start:])
+	}
+	return args
+}
+
+// parsePos parses absolute and ~ relative coordinates.
+func parsePos(args []string, origin Vec3D) (Vec3D, error) {
+	o := [3]int32{origin.X, origin.Y, origin.Z}
+	var v [3]int32
+	for i, a := range args {
+		if strings.HasPrefix(a, "^") {
+			return Vec3D{}, fmt.Errorf("local coordinates %q are not supported", a)
+		}
+		var base int32
+		if strings.HasPrefix(a, "~") {
+			base, a = o[i], a[1:]
+			if a == "" {
+				a = "0"
+			}
+		}
+		f, err := strconv.ParseFloat(a, 64)
+		if err != nil {
+			return Vec3D{}, fmt.Errorf("invalid coordinate %q", args[i])
+		}
+		v[i] = base + int32(math.Floor(f))
+	}
+	return Vec3D{v[0], v[1], v[2]}, nil
+}
+
+// parseBlock parses a block argument like minecraft:chest[facing=east]{...}.
+func parseBlock(arg string) (BlockState, *nbt.RawMessage, error) {
+	name, props, snbt, err := splitBlock(arg)
+	if err != nil {
+		return BlockState{}, nil, err
+	}
+	if strings.HasPrefix(name, "#") {
+		return BlockState{}, nil, fmt.Errorf("block tag %s can't be placed", name)
+	}
+	b, ok := block.FromID[name]
+	if !ok {
+		return BlockState{}, nil, fmt.Errorf("unknown block %s", name)
+	}
+	s := NewBlockState(withProperties(b, props))
+	if snbt == "" {
+		return s, nil, nil
+	}
+	m, err := rawMessage(nbt.StringifiedMessage(snbt))
+	if err != nil {
+		return s, nil, fmt.Errorf("invalid NBT of %s: %w", name, err)
+	}
+	if m.Type != nbt.TagCompound {
+		return s, nil, fmt.Errorf("NBT of %s is not a compound", name)
+	}
+	return s, &m, nil
+}
+
+// parseFilter parses the block or block tag of fill replace and clone
+// filtered, the properties it gives must match and the others may differ.
+func parseFilter(arg string) (Mask, error) {
+	name, props, _, err := splitBlock(arg)
+	if err != nil {
+		return nil, err
+	}
+	var masks []Mask
+	if strings.HasPrefix(name, "#") {
+		masks = append(masks, BlockTag(name))
+	} else {
+		masks = append(masks, Names(name))
+	}
+	for k, v := range props {
+		masks = append(masks, Property(k, v))
+	}
+	return And(masks...), nil
+}
+
+// splitBlock splits a block argument into its namespaced name, properties
+// and SNBT.
+func splitBlock(arg string) (string, map[string]string, string, error) {
+	name, snbt := arg, ""
+	if i := strings.IndexByte(arg, '{'); i >= 0 {
+		name, snbt = arg[:i], arg[i:]
+	}
+	var props map[string]string
+	if i := strings.IndexByte(name, '['); i >= 0 {
+		if !strings.HasSuffix(name, "]") {
+			return "", nil, "", fmt.Errorf("invalid block states %q", name[i:])
+		}
+		props = make(map[string]string)
+		for _, p := range strings.Split(name[i+1:len(name)-1], ",") {
+			if strings.TrimSpace(p) == "" {
+				continue
+			}
+			k, v, ok := strings.Cut(p, "=")
+			if !ok {
+				return "", nil, "", fmt.Errorf("invalid block state %q", p)
+			}
+			props[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+		name = name[:i]
+	}
+	if strings.HasPrefix(name, "#") {
+		return "#" + namespaced(name[1:]), props, snbt, nil
+	}
+	return namespaced(name), props, snbt, nil
+}
@@ -0,0 +1,133 @@
+package schematic
+
+import (
+	"github.com/Tnze/go-mc/level/block"
+	"strings"
+	"testing"
+)
+
+func TestImportMCFunction(t *testing.T) {
+	function := `# a small hut
+/setblock ~ ~ ~ stone
+fill ~1 ~ ~ ~3 ~2 ~2 minecraft:oak_planks hollow
+fill 10 64 10 12 64 10 glass replace stone
+fill ~1 ~1 ~1 ~1 ~1 ~1 minecraft:oak_stairs[facing=west] replace #minecraft:planks
+clone ~1 ~0 ~0 ~3 ~2 ~2 ~5 ~0 ~0 masked
+setblock ~ ~3 ~ chest[facing=east]{Items:[{Slot:0b,id:"minecraft:apple",Count:2b}],CustomName:'{"text":"a b"}'}
+fill ~ ~ ~-2 ~1 ~ ~-2 stone outline
+summon creeper ~ ~ ~
+setblock ~ ~ ~ not_a_block
+setblock ^ ^ ^1 stone
+`
+	p, report, err := ImportMCFunction("hut", strings.NewReader(function), Vec3D{10, 64, 10})
+	if err != nil {
+		t.Fatalf("Error, %v", err)
+	}
+	if report.Commands != 7 || len(report.Unsupported) != 3 || report.Unsupported[0].Line != 9 {
+		t.Fatalf("Error, report %+v", report)
+	}
+	if report.Origin != (Vec3D{10, 64, 8}) || p.Size() != (Vec3D{8, 4, 5}) {
+		t.Fatalf("Error, project at %v of size %v", report.Origin, p.Size())
+	}
+	at := func(x, y, z int) BlockState {
+		return p.GetBlock(x-10, y-64, z-8)
+	}
+	for _, c := range []struct {
+		x, y, z int
+		want    block.Block
+	}{
+		{10, 64, 10, block.Glass{}},
+		{11, 64, 10, block.OakPlanks{}},
+		{13, 64, 10, block.OakPlanks{}},
+		{12, 65, 11, block.Air{}},
+		{11, 65, 11, withProperties(block.OakStairs{}, map[string]string{"facing": "west"})},
+		{15, 64, 12, block.OakPlanks{}},
+		{17, 66, 12, block.OakPlanks{}},
+		{16, 65, 11, block.Air{}},
+		{10, 64, 8, block.Stone{}},
+		{11, 64, 8, block.Stone{}},
+	} {
+		if got := at(c.x, c.y, c.z); got != NewBlockState(c.want) {
+			t.Fatalf("Error, block at %d, %d, %d: %v, want %v", c.x, c.y, c.z, got, c.want)
+		}
+	}
+	if at(10, 67, 10).Name != "minecraft:chest" {
+		t.Fatalf("Error, no chest")
+	}
+	m, ok := p.BlockEntity(0, 3, 2)
+	if !ok || !strings.Contains(m.String(), `id:"minecraft:apple"`) {
+		t.Fatalf("Error, chest NBT %v", m)
+	}
+}
+
+func TestImportClone(t *testing.T) {
+	function := `setblock 0 0 0 stone
+setblock 1 0 0 dirt
+clone 0 0 0 1 0 0 1 0 0 replace move
+clone 1 0 0 2 0 0 0 1 0 filtered stone
+`
+	p, _, err := ImportMCFunction("clone", strings.NewReader(function), Vec3D{})
+	if err != nil {
+		t.Fatalf("Error, %v", err)
+	}
+	want := map[Vec3D]block.Block{{0, 0, 0}: block.Air{}, {1, 0, 0}: block.Stone{}, {2, 0, 0}: block.Dirt{}, {0, 1, 0}: block.Stone{}, {1, 1, 0}: block.Air{}}
+	for v, b := range want {
+		if got := p.GetBlock(int(v.X), int(v.Y), int(v.Z)); got != NewBlockState(b) {
+			t.Fatalf("Error, block at %v: %v, want %v", v, got, b)
+		}
+	}
+}
+
+func TestCommandsRoundTrip(t *testing.T) {
+	p := randomProject(13, 7, 11)
+	p.SetBlock(3, 3, 3, block.Chest{Facing: block.North})
+	_ = p.SetBlockEntity(3, 3, 3, testChest{ID: "minecraft:chest", Items: []testItem{{1, "minecraft:apple", 5}}})
+	// the corner blocks make the imported project as large as p
+	p.SetBlock(0, 0, 0, block.Stone{})
+	p.SetBlock(12, 6, 10, block.Stone{})
+	functions := p.MCFunctions(FunctionOptions{Origin: &Vec3D{-5, 70, 3}})
+	q, report, err := ImportMCFunction("copy", strings.NewReader(strings.Join(functions, "")), Vec3D{})
+	if err != nil || len(report.Unsupported) != 0 {
+		t.Fatalf("Error, %v %+v", err, report.Unsupported)
+	}
+	if report.Origin != (Vec3D{-5, 70, 3}) {
+		t.Fatalf("Error, origin %v", report.Origin)
+	}
+	p.ForEachBlock(func(x, y, z int, s BlockState) {
+		if q.GetBlock(x, y, z) != s {
+			t.Fatalf("Error, block at %d, %d, %d: %v, want %v", x, y, z, q.GetBlock(x, y, z), s)
+		}
+	})
+	a, _ := p.BlockEntity(3, 3, 3)
+	b, _ := q.BlockEntity(3, 3, 3)
+	if a.String() != b.String() {
+		t.Fatalf("Error, block entity %v, want %v", b, a)
+	}
+}
+
+func TestImportCloneOutside(t *testing.T) {
+	function := `setblock 10 10 10 stone
+clone 0 0 0 1 1 1 10 10 10 replace move
+fill 0 0 0 100 100 100 stone
+`
+	p, report, err := ImportMCFunction("outside", strings.NewReader(function), Vec3D{})
+	if err != nil {
+		t.Fatalf("Error, %v", err)
+	}
+	if len(report.Unsupported) != 1 || report.Unsupported[0].Line != 3 {
+		t.Fatalf("Error, report %+v", report)
+	}
+	if report.Origin != (Vec3D{}) || p.Size() != (Vec3D{12, 12, 12}) {
+		t.Fatalf("Error, project at %v of size %v", report.Origin, p.Size())
+	}
+	if p.GetBlock(10, 10, 10) != Air {
+		t.Fatalf("Error, the air of the source isn't cloned")
+	}
+}
+
+func TestImportTooLarge(t *testing.T) {
+	function := "setblock 0 0 0 stone\nsetblock 29999999 0 29999999 stone\n"
+	if _, _, err := ImportMCFunction("far", strings.NewReader(function), Vec3D{}); err == nil {
+		t.Fatalf("Error, blocks 30 million apart imported")
+	}
+}
@@ -0,0 +1,265 @@
+package schematic
+
+import (
+	"context"
+	"image/color"
+	"sort"
+)
+
+var defaultMeshColor = rgb(128, 128, 128)
+
+// paletteInfo caches what the mesher needs to know about one palette entry.
+type paletteInfo struct {
+	boxes       []box
+	full        bool
+	opaque      bool
+	material    int
+	transparent bool
+}
+
+// Mesh builds a triangle mesh of the visible faces of the project. Faces between
+// opaque blocks are dropped, and coplanar faces of full blocks with the same ID
+// are merged into larger rectangles (greedy meshing). Blocks that aren't full
+// cubes are approximated by a few boxes. Triangles use the block ID as material
+// name and take their colour from colors, or DefaultColorTable if it is nil.
+func (p *Project) Mesh(colors ColorTable) *Mesh {
+	m, _ := p.mesh(colors, nil)
+	return m
+}
+
+// MeshContext is Mesh stopping when ctx is done. Every block is visited
+// seven times, once per face direction and once for partial blocks, and the
+// progress counts these visits.
+func (p *Project) MeshContext(ctx context.Context, colors ColorTable, progress Progress) (*Mesh, error) {
+	return p.mesh(colors, newTracker(ctx, progress, 7*int64(p.Bounds().Volume()), progressStep))
+}
+
+func (p *Project) mesh(colors ColorTable, t *tracker) (*Mesh, error) {
+	if colors == nil {
+		colors = DefaultColorTable
+	}
+	palette := p.Palette()
+
+	var names []string
+	materials := make(map[string]int)
+	for _, s := range palette {
+		if _, ok := materials[s.Name]; !ok {
+			materials[s.Name] = 0
+			names = append(names, s.Name)
+		}
+	}
+	sort.Strings(names)
+	matColors := make([]color.RGBA, len(names))
+	for i, n := range names {
+		materials[n] = i
+		matColors[i] = defaultMeshColor
+	}
+
+	info := make([]paletteInfo, len(palette))
+	for i, s := range palette {
+		info[i] = paletteInfo{
+			boxes:       blockBoxes(s.Properties),
+			full:        isFullBlock(s.Properties),
+			opaque:      isOpaqueFullBlock(s.Properties),
+			transparent: isTransparent(s.Properties),
+			material:    materials[s.Name],
+		}
+		if c, ok := colors.ColorOf(s.Properties); ok {
+			matColors[info[i].material] = c
+		}
+	}
+
+	mb := &meshBuilder{
+		size:   [3]int{p.XRange(), p.YRange(), p.ZRange()},
+		info:   info,
+		names:  names,
+		colors: matColors,
+		m:      &Mesh{},
+		t:      t,
+	}
+	mb.at = func(c [3]int) int {
+		for a := 0; a < 3; a++ {
+			if c[a] < 0 || c[a] >= mb.size[a] {
+				return -1
+			}
+		}
+		return p.blocks.get(c[0], c[1], c[2])
+	}
+	if err := mb.greedy(); err != nil {
+		return nil, err
+	}
+	if err := mb.partial(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(mb.m.Triangles, func(i, j int) bool {
+		return mb.m.Triangles[i].Material < mb.m.Triangles[j].Material
+	})
+	return mb.m, nil
+}
+
+type meshBuilder struct {
+	size   [3]int
+	info   []paletteInfo
+	names  []string
+	colors []color.RGBA
+	at     func(c [3]int) int
+	m      *Mesh
+	t      *tracker
+}
+
+// hidden reports whether the face of block a next to block n can't be seen.
+func (mb *meshBuilder) hidden(a, n int) bool {
+	if n < 0 {
+		return false
+	}
+	if mb.info[n].opaque {
+		return true
+	}
+	return mb.info[n].full && mb.info[n].transparent && mb.info[n].material == mb.info[a].material
+}
+
+// greedy meshes the full blocks, one slice per axis and direction at a time.
+func (mb *meshBuilder) greedy() error {
+	for d := 0; d < 3; d++ {
+		u, v := (d+1)%3, (d+2)%3
+		mask := make([]int, mb.size[u]*mb.size[v])
+		for _, dir := range []int{-1, 1} {
+			for s := 0; s < mb.size[d]; s++ {
+				for j := 0; j < mb.size[v]; j++ {
+					for i := 0; i < mb.size[u]; i++ {
+						var c [3]int
+						c[d], c[u], c[v] = s, i, j
+						a := mb.at(c)
+						mask[j*mb.size[u]+i] = -1
+						if a < 0 || !mb.info[a].full {
+							continue
+						}
+						c[d] += dir
+						if !mb.hidden(a, mb.at(c)) {
+							mask[j*mb.size[u]+i] = mb.info[a].material
+						}
+					}
+				}
+				plane := float64(s)
+				if dir > 0 {
+					plane++
+				}
+				mb.mergeMask(mask, d, dir, plane)
+				if err := mb.t.add(len(mask)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// mergeMask covers the mask with as few rectangles of the same material as the
+// greedy scan finds, and emits one quad for each.
+func (mb *meshBuilder) mergeMask(mask []int, d, dir int, plane float64) {
+	u, v := (d+1)%3, (d+2)%3
+	w, h := mb.size[u], mb.size[v]
+	for j := 0; j < h; j++ {
+		for i := 0; i < w; {
+			mat := mask[j*w+i]
+			if mat < 0 {
+				i++
+				continue
+			}
+			width := 1
+			for i+width < w && mask[j*w+i+width] == mat {
+				width++
+			}
+			height := 1
+		grow:
+			for j+height < h {
+				for k := 0; k < width; k++ {
+					if mask[(j+height)*w+i+k] != mat {
+						break grow
+					}
+				}
+				height++
+			}
+			for y := 0; y < height; y++ {
+				for k := 0; k < width; k++ {
+					mask[(j+y)*w+i+k] = -1
+				}
+			}
+			var lo, hi [3]float64
+			lo[d], hi[d] = plane, plane
+			lo[u], hi[u] = float64(i), float64(i+width)
+			lo[v], hi[v] = float64(j), float64(j+height)
+			mb.quad(d, dir, lo, hi, mat)
+			i += width
+		}
+	}
+}
+
+// partial emits the boxes of every block that isn't a full cube.
+func (mb *meshBuilder) partial() error {
+	var c [3]int
+	for c[1] = 0; c[1] < mb.size[1]; c[1]++ {
+		for c[2] = 0; c[2] < mb.size[2]; c[2]++ {
+			for c[0] = 0; c[0] < mb.size[0]; c[0]++ {
+				a := mb.at(c)
+				if a < 0 || mb.info[a].full {
+					continue
+				}
+				for _, b := range mb.info[a].boxes {
+					mb.box(c, a, b)
+				}
+			}
+			if err := mb.t.add(mb.size[0]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (mb *meshBuilder) box(c [3]int, a int, b box) {
+	bmin := [3]float64{b.Min.X, b.Min.Y, b.Min.Z}
+	bmax := [3]float64{b.Max.X, b.Max.Y, b.Max.Z}
+	for d := 0; d < 3; d++ {
+		for _, dir := range []int{-1, 1} {
+			// only faces on the block boundary can be hidden by a neighbour
+			if (dir < 0 && bmin[d] == 0) || (dir > 0 && bmax[d] == 1) {
+				n := c
+				n[d] += dir
+				if nb := mb.at(n); nb >= 0 && mb.info[nb].opaque {
+					continue
+				}
+			}
+			var lo, hi [3]float64
+			for k := 0; k < 3; k++ {
+				lo[k], hi[k] = float64(c[k])+bmin[k], float64(c[k])+bmax[k]
+			}
+			if dir < 0 {
+				hi[d] = lo[d]
+			} else {
+				lo[d] = hi[d]
+			}
+			mb.quad(d, dir, lo, hi, mb.info[a].material)
+		}
+	}
+}
+
+// quad emits two triangles spanning lo-hi in the plane normal to axis d,
+// wound counter-clockwise when seen from the side dir points to.
+func (mb *meshBuilder) quad(d, dir int, lo, hi [3]float64, mat int) {
+	u, v := (d+1)%3, (d+2)%3
+	corner := func(cu, cv float64) Vec3 {
+		var p [3]float64
+		p[d], p[u], p[v] = lo[d], cu, cv
+		return Vec3{p[0], p[1], p[2]}
+	}
+	p0, p1, p2, p3 := corner(lo[u], lo[v]), corner(hi[u], lo[v]), corner(hi[u], hi[v]), corner(lo[u], hi[v])
+	if dir < 0 {
+		p1, p3 = p3, p1
+	}
+	t := Triangle{Material: mb.names[mat], Color: mb.colors[mat], HasColor: true}
+	t.V = [3]Vec3{p0, p1, p2}
+	mb.m.Triangles = append(mb.m.Triangles, t)
+	t.V = [3]Vec3{p0, p2, p3}
+	mb.m.Triangles = append(mb.m.Triangles, t)
+}
@@ -0,0 +1,89 @@
+package schematic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/Tnze/go-mc/level/block"
+	"testing"
+)
+
+func TestProjectMesh(t *testing.T) {
+	p := NewProject("mesh", 3, 2, 4)
+	for x := 0; x < 3; x++ {
+		for y := 0; y < 2; y++ {
+			for z := 0; z < 4; z++ {
+				p.SetBlock(x, y, z, block.Stone{})
+			}
+		}
+	}
+	m := p.Mesh(nil)
+	// a solid box merges to one quad per side
+	if len(m.Triangles) != 12 {
+		t.Fatalf("Error, triangles: %d, want 12", len(m.Triangles))
+	}
+	center := Vec3{1.5, 1, 2}
+	for _, tri := range m.Triangles {
+		if tri.normal().Dot(tri.V[0].Sub(center)) <= 0 {
+			t.Fatalf("Error, triangle %v faces inwards", tri.V)
+		}
+	}
+
+	// the slab doesn't hide the top of the stone, but the stone hides the slab bottom
+	p2 := NewProject("mesh", 3, 3, 4)
+	p2.SetBlock(1, 0, 1, block.Stone{})
+	p2.SetBlock(1, 1, 1, block.OakSlab{Type: block.SlabTypeBottom})
+	m2 := p2.Mesh(nil)
+	if len(m2.Triangles) != 22 {
+		t.Fatalf("Error, triangles: %d, want 22", len(m2.Triangles))
+	}
+
+	var obj bytes.Buffer
+	if err := m.WriteOBJ(&obj, "mesh.mtl"); err != nil {
+		t.Fatal(err)
+	}
+	back, err := ReadOBJ(&obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(back.Triangles) != len(m.Triangles) || back.Triangles[0].Material != "minecraft:stone" {
+		t.Fatalf("Error, obj round trip: %d triangles, material %q", len(back.Triangles), back.Triangles[0].Material)
+	}
+
+	var glb bytes.Buffer
+	if err := m2.WriteGLB(&glb); err != nil {
+		t.Fatal(err)
+	}
+	data := glb.Bytes()
+	if string(data[:4]) != "glTF" || int(binary.LittleEndian.Uint32(data[8:])) != len(data) {
+		t.Fatalf("Error, invalid glb header")
+	}
+	var doc gltfDocument
+	if err := json.Unmarshal(data[20:20+binary.LittleEndian.Uint32(data[12:])], &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Materials) != 2 || len(doc.Meshes[0].Primitives) != 2 {
+		t.Fatalf("Error, materials: %d, primitives: %d", len(doc.Materials), len(doc.Meshes[0].Primitives))
+	}
+}
+
+func TestEmptyGLB(t *testing.T) {
+	var glb bytes.Buffer
+	if err := (&Mesh{}).WriteGLB(&glb); err != nil {
+		t.Fatal(err)
+	}
+	data := glb.Bytes()
+	n := binary.LittleEndian.Uint32(data[12:])
+	if int(binary.LittleEndian.Uint32(data[8:])) != len(data) || len(data) != 20+int(n) {
+		t.Fatalf("Error, %d bytes with a JSON chunk of %d", len(data), n)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data[20:], &doc); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"meshes", "accessors", "bufferViews", "buffers"} {
+		if _, ok := doc[k]; ok {
+			t.Fatalf("Error, empty mesh has %s", k)
+		}
+	}
+}
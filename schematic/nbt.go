@@ -2,26 +2,36 @@ package schematic
 
 import (
 	"compress/gzip"
+	"fmt"
 	"github.com/Tnze/go-mc/nbt"
 	"io"
+	"math"
 	"path/filepath"
 )
 
 type Nbt struct {
-	Blocks      []Blocks     `nbt:"blocks"`
-	Entities    []Entity     `nbt:"entities"`
-	Palette     []BlockState `nbt:"palette"`
-	Size        []int32      `nbt:"size" nbt_type:"list"`
-	Author      string       `nbt:"author"`
+	Blocks   []Blocks     `nbt:"blocks"`
+	Entities []NbtEntity  `nbt:"entities"`
+	Palette  []BlockState `nbt:"palette,omitempty"`
+
+	//Palettes Variants of the palette, used instead of Palette by structures
+	//like shipwrecks. The game picks one when placing the structure.
+	Palettes    [][]BlockState `nbt:"palettes,omitempty"`
+	Size        []int32        `nbt:"size" nbt_type:"list"`
+	Author      string         `nbt:"author"`
 	DataVersion int32
+
+	//err of Project.Nbt, returned by Encode
+	err error
 }
 
 type NbtWithRawMessage struct {
-	Blocks      []Blocks         `nbt:"blocks"`
-	Entities    []nbt.RawMessage `nbt:"entities"`
-	Palette     []state          `nbt:"palette"`
-	Size        []int32          `nbt:"size" nbt_type:"list"`
-	Author      string           `nbt:"author"`
+	Blocks      []Blocks    `nbt:"blocks"`
+	Entities    []NbtEntity `nbt:"entities"`
+	Palette     []state     `nbt:"palette"`
+	Palettes    [][]state   `nbt:"palettes"`
+	Size        []int32     `nbt:"size" nbt_type:"list"`
+	Author      string      `nbt:"author"`
 	DataVersion int32
 }
 
@@ -32,6 +42,21 @@ type CompoundTag struct {
 type Blocks struct {
 	Pos   []int32 `nbt:"pos" nbt_type:"list"`
 	State int32   `nbt:"state"`
+
+	//Nbt Block entity of the block, without its position
+	Nbt *nbt.RawMessage `nbt:"nbt,omitempty"`
+}
+
+// NbtEntity is an entity of a structure file.
+type NbtEntity struct {
+	//Pos Exact position in the structure
+	Pos []float64 `nbt:"pos"`
+
+	//BlockPos Block the entity is in
+	BlockPos []int32 `nbt:"blockPos" nbt_type:"list"`
+
+	//Nbt Entity data, its id included
+	Nbt nbt.RawMessage `nbt:"nbt"`
 }
 
 func ReadNbtFile(r io.Reader) (*Nbt, error) {
@@ -45,10 +70,15 @@ func ReadNbtFile(r io.Reader) (*Nbt, error) {
 	if err != nil {
 		return nil, err
 	}
+	var palettes [][]BlockState
+	for _, p := range n.Palettes {
+		palettes = append(palettes, parseBlocks(p))
+	}
 	return &Nbt{
 		Blocks:      n.Blocks,
-		Entities:    parseEntities(n.Entities),
+		Entities:    n.Entities,
 		Palette:     parseBlocks(n.Palette),
+		Palettes:    palettes,
 		Size:        n.Size,
 		Author:      n.Author,
 		DataVersion: n.DataVersion,
@@ -56,6 +86,9 @@ func ReadNbtFile(r io.Reader) (*Nbt, error) {
 }
 
 func (n *Nbt) Encode(w io.Writer) error {
+	if n.err != nil {
+		return n.err
+	}
 	gw := gzip.NewWriter(w)
 	defer gw.Close()
 	err := nbt.NewEncoder(gw).Encode(n, "")
@@ -65,15 +98,77 @@ func (n *Nbt) Encode(w io.Writer) error {
 	return nil
 }
 
-func (n *Nbt) toProject(name string) *Project {
+// toProject converts the structure with its first palette.
+func (n *Nbt) toProject(name string) (*Project, error) {
 	name = filepath.Base(name)
-	name = name[:len(name)-len(filepath.Ext(name))]
+	return n.ProjectWithPalette(name[:len(name)-len(filepath.Ext(name))], 0)
+}
+
+// ProjectWithPalette converts the structure to a project with the palette
+// variant i, 0 for files with a single palette.
+func (n *Nbt) ProjectWithPalette(name string, i int) (*Project, error) {
+	palette, err := n.palette(i)
+	if err != nil {
+		return nil, err
+	}
+	if len(n.Size) != 3 {
+		return nil, fmt.Errorf("structure size has %d values, want 3", len(n.Size))
+	}
+	if n.Size[0] < 0 || n.Size[1] < 0 || n.Size[2] < 0 {
+		return nil, fmt.Errorf("structure size %v is negative", n.Size)
+	}
 	l := NewProject(name, int(n.Size[0]), int(n.Size[1]), int(n.Size[2]))
 	for _, v := range n.Blocks {
-		l.SetBlock(int(v.Pos[0]), int(v.Pos[1]), int(v.Pos[2]), n.Palette[v.State].Properties)
+		if v.State < 0 || int(v.State) >= len(palette) {
+			return nil, fmt.Errorf("block state %d is not in the palette of %d states", v.State, len(palette))
+		}
+		if len(v.Pos) != 3 {
+			return nil, fmt.Errorf("block position has %d values, want 3", len(v.Pos))
+		}
+		if l.Size().outOfRange(int(v.Pos[0]), int(v.Pos[1]), int(v.Pos[2])) {
+			return nil, fmt.Errorf("block position %v is outside the structure size %v", v.Pos, n.Size)
+		}
+		l.SetBlock(int(v.Pos[0]), int(v.Pos[1]), int(v.Pos[2]), palette[v.State].Properties)
+		if v.Nbt != nil {
+			if err := l.SetBlockEntity(int(v.Pos[0]), int(v.Pos[1]), int(v.Pos[2]), *v.Nbt); err != nil {
+				return nil, fmt.Errorf("block entity at %v: %w", v.Pos, err)
+			}
+		}
+	}
+	for i, e := range n.Entities {
+		var pos [3]float64
+		copy(pos[:], e.Pos)
+		// the entity's own Pos is where it was in the world when saved
+		entity, err := withEntityPos(parseEntity(e.Nbt), pos)
+		if err != nil {
+			return nil, fmt.Errorf("entity %d at %v: %w", i, e.Pos, err)
+		}
+		l.AddEntity(entity)
 	}
 	l.MetaData.Author = n.Author
-	l.MinecraftDataVersion = int32(defaultMinecraftDataVersion)
-	l.Version = int32(defaultVersion)
-	return l
+	if n.DataVersion != 0 {
+		l.MinecraftDataVersion = n.DataVersion
+	}
+	return l, nil
+}
+
+// nbtEntities converts the entities of p to the structure format.
+func (p *Project) nbtEntities() ([]NbtEntity, error) {
+	var entities []NbtEntity
+	for i, e := range p.entity.entity {
+		m, err := entityMessage(e)
+		if err != nil {
+			return nil, fmt.Errorf("entity %d (%s): %w", i, e.ID(), err)
+		}
+		pos, err := entityPos(e)
+		if err != nil {
+			return nil, fmt.Errorf("entity %d (%s): %w", i, e.ID(), err)
+		}
+		entities = append(entities, NbtEntity{
+			Pos:      pos[:],
+			BlockPos: []int32{int32(math.Floor(pos[0])), int32(math.Floor(pos[1])), int32(math.Floor(pos[2]))},
+			Nbt:      m,
+		})
+	}
+	return entities, nil
 }
@@ -0,0 +1,108 @@
+package schematic
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+)
+
+// PaletteCount returns the number of palette variants of the structure.
+func (n *Nbt) PaletteCount() int {
+	if len(n.Palettes) == 0 {
+		return 1
+	}
+	return len(n.Palettes)
+}
+
+// RandomProject converts the structure with a palette variant picked at
+// random, the same seed always picks the same variant.
+func (n *Nbt) RandomProject(name string, seed int64) (*Project, error) {
+	return n.ProjectWithPalette(name, rand.New(rand.NewSource(seed)).Intn(n.PaletteCount()))
+}
+
+func (n *Nbt) palette(i int) ([]BlockState, error) {
+	if len(n.Palettes) == 0 && i == 0 {
+		return n.Palette, nil
+	}
+	if i < 0 || i >= len(n.Palettes) {
+		return nil, fmt.Errorf("palette %d out of range, the structure has %d", i, n.PaletteCount())
+	}
+	return n.Palettes[i], nil
+}
+
+// NbtWithPalettes builds a structure file with one palette variant for every
+// project. The projects must have the same size; a block is written where
+// any of them isn't air, and the same state index refers to the block of
+// every project. Block entities come from the first project that has one at
+// a position, the entities and author from the first project.
+func NbtWithPalettes(projects ...*Project) (*Nbt, error) {
+	if len(projects) == 0 {
+		return nil, fmt.Errorf("no projects for the palettes")
+	}
+	size := projects[0].regionSize
+	for _, p := range projects[1:] {
+		if p.regionSize != size {
+			return nil, fmt.Errorf("project %q has size %v, want %v", p.RegionName, p.regionSize, size)
+		}
+	}
+	locked := make(map[*Project]bool)
+	for _, p := range projects {
+		if !locked[p] {
+			locked[p] = true
+			p.Compact()
+			p.mu.RLock()
+			defer p.mu.RUnlock()
+		}
+	}
+
+	// every combination of the states of the projects at a position becomes
+	// one entry of the palettes
+	ids := make([]int, len(projects))
+	key := make([]byte, 4*len(projects))
+	index := make(map[string]int32)
+	palettes := make([][]BlockState, len(projects))
+	var b []Blocks
+	for x := 0; x < projects[0].XRange(); x++ {
+		for y := 0; y < projects[0].YRange(); y++ {
+			for z := 0; z < projects[0].ZRange(); z++ {
+				empty := true
+				for i, p := range projects {
+					ids[i] = p.blocks.get(x, y, z)
+					binary.BigEndian.PutUint32(key[4*i:], uint32(ids[i]))
+					empty = empty && ids[i] == 0
+				}
+				if empty {
+					continue
+				}
+				s, ok := index[string(key)]
+				if !ok {
+					s = int32(len(palettes[0]))
+					index[string(key)] = s
+					for i, p := range projects {
+						palettes[i] = append(palettes[i], p.palette.value(ids[i]))
+					}
+				}
+				pos := Vec3D{int32(x), int32(y), int32(z)}
+				b = append(b, Blocks{Pos: []int32{pos.X, pos.Y, pos.Z}, State: s})
+				for i, p := range projects {
+					if m, ok := p.blockEntities[pos]; ok && ids[i] != 0 {
+						b[len(b)-1].Nbt = &m
+						break
+					}
+				}
+			}
+		}
+	}
+	entities, err := projects[0].nbtEntities()
+	if err != nil {
+		return nil, err
+	}
+	return &Nbt{
+		Blocks:      b,
+		Entities:    entities,
+		Palettes:    palettes,
+		Size:        []int32{size.X, size.Y, size.Z},
+		Author:      projects[0].MetaData.Author,
+		DataVersion: projects[0].MinecraftDataVersion,
+	}, nil
+}
@@ -0,0 +1,149 @@
+package schematic
+
+import (
+	"bytes"
+	"github.com/Tnze/go-mc/level/block"
+	"github.com/Tnze/go-mc/nbt"
+	"testing"
+)
+
+func paletteVariants() []*Project {
+	var projects []*Project
+	for _, b := range []block.Block{block.OakPlanks{}, block.SprucePlanks{}, block.BirchPlanks{}} {
+		p := NewProject("ship", 5, 4, 3)
+		p.Fill(NewBox(0, 0, 0, 4, 0, 2), Single(b), nil)
+		p.SetBlock(2, 1, 1, block.Chest{Facing: block.South})
+		projects = append(projects, p)
+	}
+	// a block only one variant has
+	projects[2].SetBlock(4, 3, 2, block.Lantern{})
+	_ = projects[0].SetBlockEntity(2, 1, 1, testChest{ID: "minecraft:chest"})
+	return projects
+}
+
+func TestNbtWithPalettes(t *testing.T) {
+	projects := paletteVariants()
+	n, err := NbtWithPalettes(projects...)
+	if err != nil {
+		t.Fatalf("Error, %v", err)
+	}
+	if len(n.Palettes) != 3 || len(n.Palette) != 0 {
+		t.Fatalf("Error, %d palettes and a single palette of %d", len(n.Palettes), len(n.Palette))
+	}
+	if len(n.Blocks) != 17 {
+		t.Fatalf("Error, %d blocks, want 17", len(n.Blocks))
+	}
+	var file bytes.Buffer
+	if err := n.Encode(&file); err != nil {
+		t.Fatalf("Error, encode: %v", err)
+	}
+	r, err := ReadNbtFile(&file)
+	if err != nil {
+		t.Fatalf("Error, read: %v", err)
+	}
+	if r.PaletteCount() != 3 {
+		t.Fatalf("Error, %d palettes read, want 3", r.PaletteCount())
+	}
+	for i, want := range projects {
+		p, err := r.ProjectWithPalette("ship", i)
+		if err != nil {
+			t.Fatalf("Error, palette %d: %v", i, err)
+		}
+		want.ForEachBlock(func(x, y, z int, s BlockState) {
+			if got := p.GetBlock(x, y, z); got != s {
+				t.Fatalf("Error, palette %d block at %d, %d, %d: %v, want %v", i, x, y, z, got, s)
+			}
+		})
+		if _, ok := p.BlockEntity(2, 1, 1); !ok {
+			t.Fatalf("Error, palette %d lost the chest", i)
+		}
+	}
+	if _, err := r.ProjectWithPalette("ship", 3); err == nil {
+		t.Fatalf("Error, palette 3 out of range accepted")
+	}
+
+	a, _ := r.RandomProject("ship", 7)
+	b, _ := r.RandomProject("ship", 7)
+	if a.GetBlock(0, 0, 0) != b.GetBlock(0, 0, 0) {
+		t.Fatalf("Error, the same seed picked different palettes")
+	}
+
+	if _, err := NbtWithPalettes(projects[0], NewProject("small", 2, 2, 2)); err == nil {
+		t.Fatalf("Error, projects of different sizes accepted")
+	}
+}
+
+func TestNbtSinglePalette(t *testing.T) {
+	p := paletteVariants()[0]
+	var file bytes.Buffer
+	if err := p.Nbt().Encode(&file); err != nil {
+		t.Fatalf("Error, encode: %v", err)
+	}
+	n, err := ReadNbtFile(&file)
+	if err != nil {
+		t.Fatalf("Error, read: %v", err)
+	}
+	if n.PaletteCount() != 1 || len(n.Palettes) != 0 {
+		t.Fatalf("Error, %d palettes", n.PaletteCount())
+	}
+	if _, err := n.ProjectWithPalette("ship", 1); err == nil {
+		t.Fatalf("Error, palette 1 of a single palette accepted")
+	}
+	n.Blocks[0].State = int32(len(n.Palette))
+	if _, err := n.ProjectWithPalette("ship", 0); err == nil {
+		t.Fatalf("Error, state out of the palette accepted")
+	}
+	n.Blocks[0].State = 0
+	n.Blocks[0].Pos = []int32{n.Size[0], 0, 0}
+	if _, err := n.ProjectWithPalette("ship", 0); err == nil {
+		t.Fatalf("Error, position outside the size accepted")
+	}
+}
+
+// badPosEntity has a Pos the structure can't read.
+type badPosEntity struct {
+	Pos string
+}
+
+func (badPosEntity) ID() string {
+	return "minecraft:marker"
+}
+
+func TestNbtEntityErrors(t *testing.T) {
+	p := paletteVariants()[0]
+	p.AddEntity(badPosEntity{Pos: "here"})
+	var file bytes.Buffer
+	if err := p.Nbt().Encode(&file); err == nil {
+		t.Fatalf("Error, entity without a position encoded")
+	}
+	if _, err := NbtWithPalettes(paletteVariants()[:1]...); err != nil {
+		t.Fatalf("Error, %v", err)
+	}
+	if _, err := NbtWithPalettes(p); err == nil {
+		t.Fatalf("Error, entity without a position accepted")
+	}
+
+	file.Reset()
+	if err := paletteVariants()[0].Nbt().Encode(&file); err != nil {
+		t.Fatalf("Error, encode: %v", err)
+	}
+	n, err := ReadNbtFile(&file)
+	if err != nil {
+		t.Fatalf("Error, read: %v", err)
+	}
+	for i := range n.Blocks {
+		if n.Blocks[i].Nbt != nil {
+			n.Blocks[i].Nbt = &nbt.RawMessage{Type: nbt.TagInt, Data: []byte{0, 0, 0, 1}}
+		}
+	}
+	if _, err := n.ProjectWithPalette("ship", 0); err == nil {
+		t.Fatalf("Error, block entity that isn't a compound accepted")
+	}
+	n.Entities = []NbtEntity{{Pos: []float64{1, 1, 1}, Nbt: nbt.RawMessage{Type: nbt.TagInt, Data: []byte{0, 0, 0, 1}}}}
+	for i := range n.Blocks {
+		n.Blocks[i].Nbt = nil
+	}
+	if _, err := n.ProjectWithPalette("ship", 0); err == nil {
+		t.Fatalf("Error, entity that isn't a compound accepted")
+	}
+}
@@ -0,0 +1,250 @@
+package schematic
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type Vec3 struct {
+	X, Y, Z float64
+}
+
+func (v Vec3) Add(o Vec3) Vec3 { return Vec3{v.X + o.X, v.Y + o.Y, v.Z + o.Z} }
+
+func (v Vec3) Sub(o Vec3) Vec3 { return Vec3{v.X - o.X, v.Y - o.Y, v.Z - o.Z} }
+
+func (v Vec3) Scale(s float64) Vec3 { return Vec3{v.X * s, v.Y * s, v.Z * s} }
+
+func (v Vec3) Dot(o Vec3) float64 { return v.X*o.X + v.Y*o.Y + v.Z*o.Z }
+
+func (v Vec3) Cross(o Vec3) Vec3 {
+	return Vec3{v.Y*o.Z - v.Z*o.Y, v.Z*o.X - v.X*o.Z, v.X*o.Y - v.Y*o.X}
+}
+
+type Triangle struct {
+	V [3]Vec3
+
+	//Material name from the OBJ usemtl statement, empty if none
+	Material string
+
+	//Color is only meaningful when HasColor is true
+	Color    color.RGBA
+	HasColor bool
+}
+
+type Mesh struct {
+	Triangles []Triangle
+
+	//MaterialLibs lists the mtllib files referenced by an OBJ file
+	MaterialLibs []string
+}
+
+// ApplyMaterials sets the colour of every triangle whose material is found in m.
+func (m *Mesh) ApplyMaterials(materials map[string]color.RGBA) {
+	for i := range m.Triangles {
+		if c, ok := materials[m.Triangles[i].Material]; ok {
+			m.Triangles[i].Color = c
+			m.Triangles[i].HasColor = true
+		}
+	}
+}
+
+// LoadOBJ reads an OBJ file and the material libraries next to it.
+func LoadOBJ(name string) (*Mesh, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, err := ReadOBJ(f)
+	if err != nil {
+		return nil, err
+	}
+	for _, lib := range m.MaterialLibs {
+		mf, err := os.Open(filepath.Join(filepath.Dir(name), lib))
+		if err != nil {
+			return nil, err
+		}
+		materials, err := ReadMTL(mf)
+		mf.Close()
+		if err != nil {
+			return nil, err
+		}
+		m.ApplyMaterials(materials)
+	}
+	return m, nil
+}
+
+// ReadOBJ parses vertices, faces and material references of a Wavefront OBJ file.
+// Polygons are triangulated as fans. Material libraries are not loaded, see LoadOBJ.
+func ReadOBJ(r io.Reader) (*Mesh, error) {
+	var (
+		m        = &Mesh{}
+		vertices []Vec3
+		material string
+		line     int
+	)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line++
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		switch fields[0] {
+		case "v":
+			v, err := parseVec3(fields[1:])
+			if err != nil {
+				return nil, fmt.Errorf("obj line %d: %w", line, err)
+			}
+			vertices = append(vertices, v)
+		case "f":
+			if len(fields) < 4 {
+				return nil, fmt.Errorf("obj line %d: face with less than 3 vertices", line)
+			}
+			face := make([]Vec3, 0, len(fields)-1)
+			for _, f := range fields[1:] {
+				idx, err := strconv.Atoi(strings.SplitN(f, "/", 2)[0])
+				if err != nil {
+					return nil, fmt.Errorf("obj line %d: %w", line, err)
+				}
+				if idx < 0 {
+					idx += len(vertices) + 1
+				}
+				if idx < 1 || idx > len(vertices) {
+					return nil, fmt.Errorf("obj line %d: vertex index %s out of range", line, f)
+				}
+				face = append(face, vertices[idx-1])
+			}
+			for i := 1; i+1 < len(face); i++ {
+				m.Triangles = append(m.Triangles, Triangle{V: [3]Vec3{face[0], face[i], face[i+1]}, Material: material})
+			}
+		case "usemtl":
+			material = strings.Join(fields[1:], " ")
+		case "mtllib":
+			m.MaterialLibs = append(m.MaterialLibs, fields[1:]...)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ReadMTL returns the diffuse colour (Kd) of every material in an MTL file.
+func ReadMTL(r io.Reader) (map[string]color.RGBA, error) {
+	var (
+		materials = make(map[string]color.RGBA)
+		name      string
+		line      int
+	)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line++
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "newmtl":
+			name = strings.Join(fields[1:], " ")
+			materials[name] = rgb(0xFF, 0xFF, 0xFF)
+		case "Kd":
+			v, err := parseVec3(fields[1:])
+			if err != nil {
+				return nil, fmt.Errorf("mtl line %d: %w", line, err)
+			}
+			materials[name] = rgb(unitToByte(v.X), unitToByte(v.Y), unitToByte(v.Z))
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return materials, nil
+}
+
+func parseVec3(fields []string) (Vec3, error) {
+	if len(fields) < 3 {
+		return Vec3{}, fmt.Errorf("expected 3 components, got %d", len(fields))
+	}
+	var c [3]float64
+	for i := range c {
+		f, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return Vec3{}, err
+		}
+		c[i] = f
+	}
+	return Vec3{c[0], c[1], c[2]}, nil
+}
+
+func unitToByte(f float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(1, f)) * 255))
+}
+
+// WriteOBJ writes the mesh as a Wavefront OBJ file. If mtlLib is not empty it
+// is referenced with mtllib, see WriteMTL.
+func (m *Mesh) WriteOBJ(w io.Writer, mtlLib string) error {
+	bw := bufio.NewWriter(w)
+	if mtlLib != "" {
+		fmt.Fprintf(bw, "mtllib %s\n", mtlLib)
+	}
+	vertices := make(map[Vec3]int)
+	normals := make(map[Vec3]int)
+	faces := make([][3][2]int, len(m.Triangles))
+	for i, t := range m.Triangles {
+		n := t.normal()
+		if _, ok := normals[n]; !ok {
+			normals[n] = len(normals) + 1
+			fmt.Fprintf(bw, "vn %g %g %g\n", n.X, n.Y, n.Z)
+		}
+		for k, v := range t.V {
+			if _, ok := vertices[v]; !ok {
+				vertices[v] = len(vertices) + 1
+				fmt.Fprintf(bw, "v %g %g %g\n", v.X, v.Y, v.Z)
+			}
+			faces[i][k] = [2]int{vertices[v], normals[n]}
+		}
+	}
+	material := ""
+	for i, t := range m.Triangles {
+		if t.Material != material && mtlLib != "" {
+			material = t.Material
+			fmt.Fprintf(bw, "usemtl %s\n", material)
+		}
+		f := faces[i]
+		fmt.Fprintf(bw, "f %d//%d %d//%d %d//%d\n", f[0][0], f[0][1], f[1][0], f[1][1], f[2][0], f[2][1])
+	}
+	return bw.Flush()
+}
+
+// WriteMTL writes the colour of every material used by the mesh.
+func (m *Mesh) WriteMTL(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	seen := make(map[string]bool)
+	for _, t := range m.Triangles {
+		if seen[t.Material] || !t.HasColor {
+			continue
+		}
+		seen[t.Material] = true
+		fmt.Fprintf(bw, "newmtl %s\nKd %.4f %.4f %.4f\n\n", t.Material,
+			float64(t.Color.R)/255, float64(t.Color.G)/255, float64(t.Color.B)/255)
+	}
+	return bw.Flush()
+}
+
+func (t Triangle) normal() Vec3 {
+	n := t.V[1].Sub(t.V[0]).Cross(t.V[2].Sub(t.V[0]))
+	l := math.Sqrt(n.Dot(n))
+	if l == 0 {
+		return n
+	}
+	return n.Scale(1 / l)
+}
@@ -0,0 +1,79 @@
+package schematic
+
+import "time"
+
+// Option sets a property of a project when it is created with NewProject, or
+// of the file written by Encode without changing the project.
+type Option func(*options)
+
+type options struct {
+	author      *string
+	description *string
+	dataVersion *int32
+	version     *int32
+	time        *time.Time
+}
+
+// WithAuthor sets the author of the project.
+func WithAuthor(s string) Option {
+	return func(o *options) { o.author = &s }
+}
+
+// WithDescription sets the description of the project.
+func WithDescription(s string) Option {
+	return func(o *options) { o.description = &s }
+}
+
+// WithDataVersion sets the Minecraft data version, e.g. 3465 for 1.20.1.
+func WithDataVersion(v int) Option {
+	return func(o *options) {
+		v := int32(v)
+		o.dataVersion = &v
+	}
+}
+
+// WithVersion sets the version of the litematic format.
+func WithVersion(v int) Option {
+	return func(o *options) {
+		v := int32(v)
+		o.version = &v
+	}
+}
+
+// WithTime sets the creation and modification time of a new project, or the
+// modification time of an encoded file, which is now by default. A fixed time
+// makes files reproducible.
+func WithTime(t time.Time) Option {
+	return func(o *options) { o.time = &t }
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// apply sets the options in the header of a project or file, created tells
+// whether the time is also the creation time.
+func (o *options) apply(h *LitematicHeader, created bool) {
+	if o.author != nil {
+		h.Metadata.Author = *o.author
+	}
+	if o.description != nil {
+		h.Metadata.Description = *o.description
+	}
+	if o.dataVersion != nil {
+		h.MinecraftDataVersion = *o.dataVersion
+	}
+	if o.version != nil {
+		h.Version = *o.version
+	}
+	if o.time != nil {
+		h.Metadata.TimeModified = o.time.UnixMilli()
+		if created {
+			h.Metadata.TimeCreated = h.Metadata.TimeModified
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package schematic
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProjectOptions(t *testing.T) {
+	created := time.Date(2023, 6, 7, 0, 0, 0, 0, time.UTC)
+	p := NewProject("options", 2, 2, 2, WithAuthor("Alex"), WithDescription("a test"), WithDataVersion(3465), WithVersion(6), WithTime(created))
+	if p.MetaData.Author != "Alex" || p.MetaData.Description != "a test" || p.MinecraftDataVersion != 3465 ||
+		p.MetaData.TimeCreated != created.UnixMilli() || p.MetaData.TimeModified != created.UnixMilli() {
+		t.Fatalf("Error, options not set: %+v", p.MetaData)
+	}
+
+	// the options of Encode only change the file
+	var buf bytes.Buffer
+	if err := p.Encode(&buf, WithAuthor("Steve"), WithDataVersion(3120), WithTime(created)); err != nil {
+		t.Fatalf("Error, encode: %v", err)
+	}
+	h, err := ReadLitematicaHeader(&buf)
+	if err != nil {
+		t.Fatalf("Error, read: %v", err)
+	}
+	if h.Metadata.Author != "Steve" || h.MinecraftDataVersion != 3120 || h.Metadata.TimeModified != created.UnixMilli() || p.MetaData.Author != "Alex" {
+		t.Fatalf("Error, encoded header: %+v", h)
+	}
+
+	// the structure file keeps the data version of the project
+	buf.Reset()
+	if err := p.Nbt().Encode(&buf); err != nil {
+		t.Fatalf("Error, encode nbt: %v", err)
+	}
+	q, err := LoadFromNbt("options.nbt", &buf)
+	if err != nil {
+		t.Fatalf("Error, load nbt: %v", err)
+	}
+	if q.MinecraftDataVersion != 3465 {
+		t.Fatalf("Error, data version: %d", q.MinecraftDataVersion)
+	}
+}
+
+func TestProjectOptionsConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			author := fmt.Sprint("user", i)
+			p := NewProject("options", 1, 1, 1, WithAuthor(author))
+			if p.MetaData.Author != author {
+				t.Errorf("Error, author %q, want %q", p.MetaData.Author, author)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
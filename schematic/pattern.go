@@ -0,0 +1,230 @@
+package schematic
+
+import (
+	"github.com/Tnze/go-mc/level/block"
+	"math"
+	"math/rand"
+)
+
+// Pattern chooses the block placed at each position by fill and shape operations.
+type Pattern interface {
+	At(x, y, z int) BlockState
+}
+
+type singlePattern BlockState
+
+func (s singlePattern) At(x, y, z int) BlockState { return BlockState(s) }
+
+// Single is a pattern of one block everywhere.
+func Single(b block.Block) Pattern {
+	return singlePattern(NewBlockState(b))
+}
+
+type WeightedBlock struct {
+	Block  block.Block
+	Weight float64
+}
+
+type weightedPattern struct {
+	seed   uint64
+	states []BlockState
+	cum    []float64
+}
+
+// Weighted picks one of the blocks at random at every position, in proportion
+// to their weights. The choice only depends on the seed and the position, so
+// the same seed always gives the same result whatever order blocks are set in.
+func Weighted(seed int64, blocks ...WeightedBlock) Pattern {
+	w := &weightedPattern{seed: uint64(seed)}
+	total := 0.0
+	for _, b := range blocks {
+		if b.Weight <= 0 {
+			continue
+		}
+		total += b.Weight
+		w.states = append(w.states, NewBlockState(b.Block))
+		w.cum = append(w.cum, total)
+	}
+	for i := range w.cum {
+		w.cum[i] /= total
+	}
+	return w
+}
+
+func (w *weightedPattern) At(x, y, z int) BlockState {
+	if len(w.states) == 0 {
+		return Air
+	}
+	r := positionRandom(w.seed, x, y, z)
+	for i, c := range w.cum {
+		if r < c {
+			return w.states[i]
+		}
+	}
+	return w.states[len(w.states)-1]
+}
+
+// positionRandom returns a number in [0, 1) from a hash of the seed and position.
+func positionRandom(seed uint64, x, y, z int) float64 {
+	h := splitmix64(seed ^ splitmix64(uint64(x)^splitmix64(uint64(y)^splitmix64(uint64(z)))))
+	return float64(h>>11) / (1 << 53)
+}
+
+func splitmix64(x uint64) uint64 {
+	x += 0x9E3779B97F4A7C15
+	x = (x ^ (x >> 30)) * 0xBF58476D1CE4E5B9
+	x = (x ^ (x >> 27)) * 0x94D049BB133111EB
+	return x ^ (x >> 31)
+}
+
+type noisePattern struct {
+	perm   [512]int
+	scale  float64
+	states []BlockState
+}
+
+// Noise blends between the blocks, in the order given, following 3D Perlin
+// noise. Scale is the size in blocks of the noise features.
+func Noise(seed int64, scale float64, blocks ...block.Block) Pattern {
+	n := &noisePattern{scale: scale}
+	if n.scale <= 0 {
+		n.scale = 1
+	}
+	for _, b := range blocks {
+		n.states = append(n.states, NewBlockState(b))
+	}
+	p := rand.New(rand.NewSource(seed)).Perm(256)
+	for i := 0; i < 512; i++ {
+		n.perm[i] = p[i&255]
+	}
+	return n
+}
+
+func (n *noisePattern) At(x, y, z int) BlockState {
+	if len(n.states) == 0 {
+		return Air
+	}
+	v := (n.perlin(float64(x)/n.scale, float64(y)/n.scale, float64(z)/n.scale) + 1) / 2
+	i := int(v * float64(len(n.states)))
+	return n.states[clampInt(i, 0, len(n.states)-1)]
+}
+
+// perlin is Ken Perlin's improved noise, returning values in about [-1, 1].
+func (n *noisePattern) perlin(x, y, z float64) float64 {
+	fx, fy, fz := math.Floor(x), math.Floor(y), math.Floor(z)
+	X, Y, Z := int(fx)&255, int(fy)&255, int(fz)&255
+	x, y, z = x-fx, y-fy, z-fz
+	u, v, w := fade(x), fade(y), fade(z)
+	p := &n.perm
+	A, B := p[X]+Y, p[X+1]+Y
+	AA, AB, BA, BB := p[A]+Z, p[A+1]+Z, p[B]+Z, p[B+1]+Z
+	return lerp(w,
+		lerp(v, lerp(u, grad(p[AA], x, y, z), grad(p[BA], x-1, y, z)),
+			lerp(u, grad(p[AB], x, y-1, z), grad(p[BB], x-1, y-1, z))),
+		lerp(v, lerp(u, grad(p[AA+1], x, y, z-1), grad(p[BA+1], x-1, y, z-1)),
+			lerp(u, grad(p[AB+1], x, y-1, z-1), grad(p[BB+1], x-1, y-1, z-1))))
+}
+
+func fade(t float64) float64 { return t * t * t * (t*(t*6-15) + 10) }
+
+func lerp(t, a, b float64) float64 { return a + t*(b-a) }
+
+func grad(hash int, x, y, z float64) float64 {
+	h := hash & 15
+	u, v := y, z
+	if h < 8 {
+		u = x
+	}
+	if h < 4 {
+		v = y
+	} else if h == 12 || h == 14 {
+		v = x
+	}
+	if h&1 != 0 {
+		u = -u
+	}
+	if h&2 != 0 {
+		v = -v
+	}
+	return u + v
+}
+
+type checkerPattern struct {
+	size int
+	a, b BlockState
+}
+
+// Checkerboard alternates a and b in cubes of size blocks.
+func Checkerboard(size int, a, b block.Block) Pattern {
+	return checkerPattern{max(size, 1), NewBlockState(a), NewBlockState(b)}
+}
+
+func (c checkerPattern) At(x, y, z int) BlockState {
+	if (floorDiv(x, c.size)+floorDiv(y, c.size)+floorDiv(z, c.size))&1 == 0 {
+		return c.a
+	}
+	return c.b
+}
+
+type stripePattern struct {
+	axis   Axis
+	width  int
+	states []BlockState
+}
+
+// Stripes repeats the blocks in layers of width blocks along axis.
+func Stripes(axis Axis, width int, blocks ...block.Block) Pattern {
+	s := stripePattern{axis: axis, width: max(width, 1)}
+	for _, b := range blocks {
+		s.states = append(s.states, NewBlockState(b))
+	}
+	return s
+}
+
+func (s stripePattern) At(x, y, z int) BlockState {
+	if len(s.states) == 0 {
+		return Air
+	}
+	c := y
+	switch s.axis {
+	case AxisX:
+		c = x
+	case AxisZ:
+		c = z
+	}
+	return s.states[floorMod(floorDiv(c, s.width), len(s.states))]
+}
+
+type tilePattern struct {
+	src    *Project
+	offset [3]int
+}
+
+// Tile repeats the blocks of src in every direction, like pasting a clipboard
+// over and over. The tile at offset starts with the block at (0, 0, 0) of src.
+// It panics if src has no blocks to repeat.
+func Tile(src *Project, offset [3]int) Pattern {
+	if src == nil || src.Bounds().Empty() {
+		panic("Tile of an empty project")
+	}
+	return tilePattern{src, offset}
+}
+
+func (t tilePattern) At(x, y, z int) BlockState {
+	return t.src.GetBlock(
+		floorMod(x-t.offset[0], t.src.XRange()),
+		floorMod(y-t.offset[1], t.src.YRange()),
+		floorMod(z-t.offset[2], t.src.ZRange()))
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+func floorMod(a, b int) int {
+	return a - floorDiv(a, b)*b
+}
@@ -0,0 +1,69 @@
+package schematic
+
+import (
+	"github.com/Tnze/go-mc/level/block"
+	"testing"
+)
+
+func TestWeightedPattern(t *testing.T) {
+	blocks := []WeightedBlock{
+		{block.StoneBricks{}, 60},
+		{block.CrackedStoneBricks{}, 30},
+		{block.MossyStoneBricks{}, 10},
+	}
+	a, b := Weighted(42, blocks...), Weighted(42, blocks...)
+	count := make(map[string]int)
+	for x := 0; x < 100; x++ {
+		for z := 0; z < 100; z++ {
+			s := a.At(x, 0, z)
+			if s != b.At(x, 0, z) {
+				t.Fatalf("Error, same seed gives different blocks at %d, 0, %d", x, z)
+			}
+			count[s.Name]++
+		}
+	}
+	if c := count["minecraft:stone_bricks"]; c < 5500 || c > 6500 {
+		t.Fatalf("Error, stone bricks: %d of 10000, want about 6000", c)
+	}
+	if c := count["minecraft:mossy_stone_bricks"]; c < 700 || c > 1300 {
+		t.Fatalf("Error, mossy stone bricks: %d of 10000, want about 1000", c)
+	}
+}
+
+func TestPatterns(t *testing.T) {
+	c := Checkerboard(2, block.WhiteWool{}, block.BlackWool{})
+	if c.At(0, 0, 0) != c.At(1, 1, 1) || c.At(0, 0, 0) == c.At(2, 0, 0) || c.At(-1, 0, 0) == c.At(0, 0, 0) {
+		t.Fatalf("Error, checkerboard is wrong")
+	}
+
+	s := Stripes(AxisX, 1, block.Stone{}, block.Dirt{}, block.Sand{})
+	if s.At(0, 5, 5).Name != "minecraft:stone" || s.At(4, 0, 0).Name != "minecraft:dirt" || s.At(-1, 0, 0).Name != "minecraft:sand" {
+		t.Fatalf("Error, stripes are wrong")
+	}
+
+	n := Noise(7, 8, block.Stone{}, block.Andesite{}, block.Gravel{})
+	seen := make(map[string]bool)
+	for x := 0; x < 64; x++ {
+		for z := 0; z < 64; z++ {
+			seen[n.At(x, 0, z).Name] = true
+		}
+	}
+	if len(seen) < 2 {
+		t.Fatalf("Error, noise only gives %v", seen)
+	}
+
+	src := NewProject("tile", 2, 1, 3)
+	src.SetBlock(1, 0, 2, block.Glowstone{})
+	tile := Tile(src, [3]int{0, 0, 0})
+	if tile.At(3, 4, 5).Name != "minecraft:glowstone" || tile.At(-1, 0, -1).Name != "minecraft:glowstone" || tile.At(0, 0, 2).Name != air {
+		t.Fatalf("Error, tiling is wrong")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("Error, tile of an empty project accepted")
+			}
+		}()
+		Tile(NewProject("empty", 0, 1, 1), [3]int{})
+	}()
+}
@@ -0,0 +1,101 @@
+package schematic
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Progress is called by the Context variants of long operations with the
+// work done so far and the total work. Both are counted in blocks unless the
+// operation says otherwise. It is called on the goroutine doing the work, so
+// it should return quickly.
+type Progress func(done, total int64)
+
+// progressStep is the work between two progress reports, which is also how
+// often the context is checked.
+const progressStep = 1 << 16
+
+// tracker reports the progress of an operation and checks its context. A nil
+// tracker does nothing, for the variants without context.
+type tracker struct {
+	ctx         context.Context
+	fn          Progress
+	done, total int64
+	step, next  int64
+}
+
+func newTracker(ctx context.Context, fn Progress, total, step int64) *tracker {
+	return &tracker{ctx: ctx, fn: fn, total: total, step: step, next: step}
+}
+
+// add records n more work, every step and at the end it reports the progress
+// and returns the error of the context if it is done.
+func (t *tracker) add(n int) error {
+	if t == nil {
+		return nil
+	}
+	t.done += int64(n)
+	if t.done < t.next && t.done < t.total {
+		return nil
+	}
+	t.next = t.done + t.step
+	if t.fn != nil {
+		t.fn(t.done, t.total)
+	}
+	return t.ctx.Err()
+}
+
+// err returns the error of the context.
+func (t *tracker) err() error {
+	if t == nil {
+		return nil
+	}
+	return t.ctx.Err()
+}
+
+// progressReader counts the bytes read and stops reading once the context is
+// done.
+type progressReader struct {
+	r io.Reader
+	t *tracker
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	if err := r.t.err(); err != nil {
+		return 0, err
+	}
+	n, err := r.r.Read(b)
+	if e := r.t.add(n); e != nil && err == nil {
+		err = e
+	}
+	return n, err
+}
+
+// LoadContext is LoadFromFile stopping when ctx is done. The progress is
+// counted in bytes of the file.
+func LoadContext(ctx context.Context, file *os.File, progress Progress) (*Project, error) {
+	var size int64
+	if info, err := file.Stat(); err == nil {
+		size = info.Size()
+	}
+	r := &progressReader{file, newTracker(ctx, progress, size, progressStep)}
+	var p *Project
+	var err error
+	switch ext := filepath.Ext(file.Name()); ext {
+	case ".litematic":
+		p, err = LoadFromLitematic(r)
+	case ".nbt":
+		p, err = LoadFromNbt(file.Name(), r)
+	case ".vox":
+		p, err = LoadFromVox(file.Name(), r, VoxOptions{})
+	default:
+		return LoadFromFile(file)
+	}
+	if ctx.Err() != nil {
+		// the decoders wrap the error of the reader
+		return nil, ctx.Err()
+	}
+	return p, err
+}
@@ -0,0 +1,78 @@
+package schematic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/Tnze/go-mc/level/block"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestContextProgress(t *testing.T) {
+	p := NewProject("progress", 64, 64, 64)
+	var last, total int64
+	progress := func(done, all int64) {
+		if done < last || done > all {
+			t.Fatalf("Error, progress %d of %d after %d", done, all, last)
+		}
+		last, total = done, all
+	}
+	n, err := p.FillContext(context.Background(), p.Bounds(), Single(block.Stone{}), nil, progress)
+	if err != nil || n != 64*64*64 || last != total || total != 64*64*64 {
+		t.Fatalf("Error, filled %d, progress %d of %d: %v", n, last, total, err)
+	}
+
+	var buf bytes.Buffer
+	last = 0
+	if err := p.EncodeContext(context.Background(), &buf, progress); err != nil || last != total || total != 64*64*64 {
+		t.Fatalf("Error, encode progress %d of %d: %v", last, total, err)
+	}
+
+	name := filepath.Join(t.TempDir(), "progress.litematic")
+	if err := os.WriteFile(name, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	last = 0
+	q, err := LoadContext(context.Background(), f, progress)
+	if err != nil || q.MetaData.TotalBlocks != 64*64*64 || last != int64(buf.Len()) {
+		t.Fatalf("Error, load progress %d of %d bytes: %v", last, buf.Len(), err)
+	}
+
+	last = 0
+	if _, err := p.MeshContext(context.Background(), nil, progress); err != nil || last != total || total != 7*64*64*64 {
+		t.Fatalf("Error, mesh progress %d of %d: %v", last, total, err)
+	}
+}
+
+func TestContextCancel(t *testing.T) {
+	p := NewProject("cancel", 64, 64, 64)
+	ctx, cancel := context.WithCancel(context.Background())
+	// cancel as soon as the work starts
+	stop := func(done, total int64) { cancel() }
+	if _, err := p.FillContext(ctx, p.Bounds(), Single(block.Stone{}), nil, stop); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Error, fill: %v", err)
+	}
+	if p.MetaData.TotalBlocks != 0 {
+		t.Fatalf("Error, cancelled fill set %d blocks", p.MetaData.TotalBlocks)
+	}
+	if err := p.EncodeContext(ctx, &bytes.Buffer{}, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Error, encode: %v", err)
+	}
+	if _, err := p.MeshContext(ctx, nil, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Error, mesh: %v", err)
+	}
+	cube, err := LoadOBJ("testdata/cube.obj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VoxelizeContext(ctx, "cube", cube, VoxelizeOptions{Resolution: 8}, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Error, voxelize: %v", err)
+	}
+}
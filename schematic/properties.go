@@ -0,0 +1,73 @@
+package schematic
+
+import (
+	"encoding"
+	"github.com/Tnze/go-mc/level/block"
+	"reflect"
+)
+
+// properties returns the block state properties of b in their text form,
+// e.g. {"facing": "north", "waterlogged": "false"}.
+func properties(b block.Block) map[string]string {
+	v := reflect.ValueOf(b)
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	m := make(map[string]string, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Tag.Get("nbt")
+		if name == "" {
+			continue
+		}
+		if t, ok := v.Field(i).Interface().(encoding.TextMarshaler); ok {
+			text, err := t.MarshalText()
+			if err != nil {
+				continue
+			}
+			m[name] = string(text)
+		}
+	}
+	return m
+}
+
+// property returns a single property of b, or "" if b doesn't have it.
+func property(b block.Block, name string) string {
+	v := reflect.ValueOf(b)
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).Tag.Get("nbt") != name {
+			continue
+		}
+		if t, ok := v.Field(i).Interface().(encoding.TextMarshaler); ok {
+			if text, err := t.MarshalText(); err == nil {
+				return string(text)
+			}
+		}
+	}
+	return ""
+}
+
+// withProperties returns b with the properties in props set, those b doesn't
+// have or with invalid values are ignored.
+func withProperties(b block.Block, props map[string]string) block.Block {
+	v := reflect.ValueOf(b)
+	if v.Kind() != reflect.Struct || len(props) == 0 {
+		return b
+	}
+	n := reflect.New(v.Type()).Elem()
+	n.Set(v)
+	for i := 0; i < n.NumField(); i++ {
+		text, ok := props[n.Type().Field(i).Tag.Get("nbt")]
+		if !ok {
+			continue
+		}
+		f := n.Field(i).Addr().Interface().(encoding.TextUnmarshaler)
+		old := n.Field(i).Interface()
+		if f.UnmarshalText([]byte(text)) != nil {
+			n.Field(i).Set(reflect.ValueOf(old))
+		}
+	}
+	return n.Interface().(block.Block)
+}
@@ -1,7 +1,6 @@
 package schematic
 
 import (
-	"compress/gzip"
 	"fmt"
 	"github.com/Tnze/go-mc/level/block"
 	"github.com/Tnze/go-mc/nbt"
@@ -9,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -17,24 +17,68 @@ var (
 	defaultDescription          = ""
 	defaultMinecraftDataVersion = 2975
 	defaultVersion              = 6
+
+	//defaultsMu guards the defaults above
+	defaultsMu sync.RWMutex
 )
 
+// SetDefaultAuthor sets the author of the projects created afterwards.
+//
+// Deprecated: the default is shared by the whole program, use WithAuthor.
 func SetDefaultAuthor(s string) {
+	defaultsMu.Lock()
+	defer defaultsMu.Unlock()
 	defaultAuthor = s
 }
 
+// SetDefaultDescription sets the description of the projects created
+// afterwards.
+//
+// Deprecated: the default is shared by the whole program, use WithDescription.
 func SetDefaultDescription(s string) {
+	defaultsMu.Lock()
+	defer defaultsMu.Unlock()
 	defaultDescription = s
 }
 
+// SetDefaultMinecraftDataVersion sets the data version of the projects
+// created afterwards.
+//
+// Deprecated: the default is shared by the whole program, use WithDataVersion.
 func SetDefaultMinecraftDataVersion(v int) {
+	defaultsMu.Lock()
+	defer defaultsMu.Unlock()
 	defaultMinecraftDataVersion = v
 }
 
+// SetDefaultVersion sets the litematic version of the projects created
+// afterwards.
+//
+// Deprecated: the default is shared by the whole program, use WithVersion.
 func SetDefaultVersion(v int) {
+	defaultsMu.Lock()
+	defer defaultsMu.Unlock()
 	defaultVersion = v
 }
 
+// defaultHeader returns the metadata and versions of a new project.
+func defaultHeader() LitematicHeader {
+	defaultsMu.RLock()
+	defer defaultsMu.RUnlock()
+	now := time.Now().UnixMilli()
+	return LitematicHeader{
+		Metadata: Metadata{
+			Author:       defaultAuthor,
+			Description:  defaultDescription,
+			RegionCount:  1,
+			TimeCreated:  now,
+			TimeModified: now,
+		},
+		MinecraftDataVersion: int32(defaultMinecraftDataVersion),
+		Version:              int32(defaultVersion),
+	}
+}
+
 type Project struct {
 	MetaData Metadata
 
@@ -47,31 +91,51 @@ type Project struct {
 
 	regionSize Vec3D
 
-	data *BitArray
+	blocks blockStorage
 
 	palette *blockStatePalette
 
 	entity *entityContainer
+
+	//blockEntities NBT of the block entities by position, without x, y, z
+	blockEntities map[Vec3D]nbt.RawMessage
+
+	//mu guards blocks, blockEntities and MetaData.TotalBlocks, so blocks can be set and read
+	//from several goroutines
+	mu sync.RWMutex
+
+	//writes Counts the changes to blocks, so a walk sees the blocks set in the row it decoded
+	writes atomic.Uint64
+}
+
+// NewProject returns an empty project of x by y by z blocks. Its author,
+// description and versions are the defaults unless set by opts, e.g.
+// NewProject("house", 16, 16, 16, WithAuthor("Steve"), WithDataVersion(3465)).
+func NewProject(name string, x, y, z int, opts ...Option) *Project {
+	return newProject(name, x, y, z, newDenseStorage(Vec3D{int32(x), int32(y), int32(z)}), opts)
 }
 
-func NewProject(name string, x, y, z int) *Project {
+// NewSparseProject returns a project that allocates its blocks in 16x16x16
+// sections when they are first set, and only builds the dense Litematica
+// layout when it is encoded. It saves memory for large projects that are
+// mostly air, see BenchmarkStorage.
+func NewSparseProject(name string, x, y, z int, opts ...Option) *Project {
+	return newProject(name, x, y, z, newSparseStorage(Vec3D{int32(x), int32(y), int32(z)}), opts)
+}
+
+func newProject(name string, x, y, z int, blocks blockStorage, opts []Option) *Project {
+	h := defaultHeader()
+	newOptions(opts).apply(&h, true)
+	h.Metadata.EnclosingSize = Vec3D{int32(x), int32(y), int32(z)}
+	h.Metadata.Name = name
+	h.Metadata.TotalVolume = int32(x * y * z)
 	return &Project{
-		MetaData: Metadata{
-			Author:        defaultAuthor,
-			Description:   defaultDescription,
-			EnclosingSize: Vec3D{int32(x), int32(y), int32(z)},
-			Name:          name,
-			RegionCount:   1,
-			TimeCreated:   time.Now().UnixMilli(),
-			TimeModified:  time.Now().UnixMilli(),
-			TotalBlocks:   0,
-			TotalVolume:   int32(x * y * z),
-		},
-		MinecraftDataVersion: int32(defaultMinecraftDataVersion),
-		Version:              int32(defaultVersion),
+		MetaData:             h.Metadata,
+		MinecraftDataVersion: h.MinecraftDataVersion,
+		Version:              h.Version,
 		RegionName:           name,
 		regionSize:           Vec3D{int32(x), int32(y), int32(z)},
-		data:                 NewEmptyBitArray(x * y * z),
+		blocks:               blocks,
 		palette:              newBlockStatePalette(),
 		entity:               newEntityContainer(),
 	}
@@ -83,6 +147,8 @@ func LoadFromFile(file *os.File) (*Project, error) {
 		return LoadFromLitematic(file)
 	} else if ext == ".nbt" {
 		return LoadFromNbt(file.Name(), file)
+	} else if ext == ".vox" {
+		return LoadFromVox(file.Name(), file, VoxOptions{})
 	} else {
 		return nil, fmt.Errorf("unsuppot file format: %s", ext)
 	}
@@ -101,41 +167,62 @@ func LoadFromNbt(name string, f io.Reader) (*Project, error) {
 	if err != nil {
 		return nil, err
 	}
-	return n.toProject(name), nil
-}
-
-func (p *Project) index(x, y, z int) int {
-	return p.MetaData.EnclosingSize.getIndex(x, y, z)
+	return n.toProject(name)
 }
 
+// Data returns the blocks in the Litematica layout, which is built for
+// sparse projects.
 func (p *Project) Data() []int64 {
-	return p.data.data
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.blocks.dense().data
 }
 
 func (p *Project) Palette() []BlockState {
-	return p.palette.palette
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.paletteSnapshot()
 }
 
 var Air = NewBlockState(block.Air{})
 
+// GetBlock returns the block at x, y, z, it is safe to call while other
+// goroutines set blocks.
 func (p *Project) GetBlock(x, y, z int) BlockState {
 	if p.MetaData.EnclosingSize.outOfRange(x, y, z) {
 		return Air // return air if out of range
 	}
-	return p.palette.value(p.data.getBlock(int64(p.index(x, y, z))))
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.palette.value(p.blocks.get(x, y, z))
 }
 
+// SetBlock sets the block at x, y, z, it is safe to call from several
+// goroutines. For many blocks from parallel workers a Batch locks less.
 func (p *Project) SetBlock(x, y, z int, b block.Block) {
 	if p.MetaData.EnclosingSize.outOfRange(x, y, z) {
 		panic(fmt.Sprintf("SetBlock out of range : enclosingSize: %v,Pos: %d, %d, %d", p.MetaData.EnclosingSize, x, y, z))
 	}
-	var oldState = p.GetBlock(x, y, z)
-	if oldState.Name == air && b.ID() != air {
+	s := NewBlockState(b)
+	// the id is taken under p.mu, so Compact can't renumber the palette
+	// before it is stored
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.setID(x, y, z, p.palette.id(s))
+}
+
+// setID stores the palette index id at x, y, z and keeps TotalBlocks, p.mu
+// must be held.
+func (p *Project) setID(x, y, z, id int) {
+	wasAir := p.palette.value(p.blocks.get(x, y, z)).Name == air
+	isAir := p.palette.value(id).Name == air
+	if wasAir && !isAir {
 		p.MetaData.TotalBlocks++
-	} else if oldState.Name != air && b.ID() == air {
+	} else if !wasAir && isAir {
 		p.MetaData.TotalBlocks--
 	}
-	p.data.setBlock(int64(p.index(x, y, z)), p.palette.id(NewBlockState(b)))
+	p.blocks.set(x, y, z, id)
+	p.writes.Add(1)
 }
 
 func (p *Project) Contain(block BlockState) bool {
@@ -162,36 +249,30 @@ func (p *Project) Size() Vec3D {
 	return p.MetaData.EnclosingSize
 }
 
+// ChangeMaterial replaces every block in the exact state from with to.
 func (p *Project) ChangeMaterial(from, to block.Block) {
-	p.palette.changeMaterial(NewBlockState(from), NewBlockState(to))
-}
-
-// Encode default encode litematic file
-func (p *Project) Encode(w io.Writer) error {
-	gw := gzip.NewWriter(w)
-	defer gw.Close()
-	err := nbt.NewEncoder(gw).Encode(p.Litematic(), "")
-	if err != nil {
-		return err
-	}
-	return nil
+	p.ChangeMaterials(map[BlockState]BlockState{NewBlockState(from): NewBlockState(to)})
 }
 
+// region returns the project as its only region, p.mu must be held.
 func (p *Project) region() map[string]Region {
 	rs := make(map[string]Region)
 	r := Region{
-		BlockStatePalette: p.palette.palette,
-		TileEntities:      []CompoundTag{},
+		BlockStatePalette: p.paletteSnapshot(),
+		TileEntities:      p.tileEntities(),
 		Entities:          p.entity.entity,
 		Position:          Vec3D{},
 		Size:              p.regionSize,
-		BlockStates:       p.data.data,
+		BlockStates:       p.blocks.dense().data,
 	}
 	rs[p.RegionName] = r
 	return rs
 }
 
 func (p *Project) Litematic() *Litematic {
+	p.Compact()
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	project := &Litematic{
 		Metadata:             p.MetaData,
 		MinecraftDataVersion: p.MinecraftDataVersion,
@@ -202,25 +283,36 @@ func (p *Project) Litematic() *Litematic {
 	return project
 }
 
+// Nbt returns the project as a structure file. Entities that can't be
+// converted make its Encode return the error.
 func (p *Project) Nbt() *Nbt {
+	p.Compact()
+	p.mu.RLock()
 	var b []Blocks
 	for x := 0; x < p.XRange(); x++ {
 		for y := 0; y < p.YRange(); y++ {
 			for z := 0; z < p.ZRange(); z++ {
-				s := int32(p.data.getBlock(int64(p.index(x, y, z))))
+				s := int32(p.blocks.get(x, y, z))
 				if s != 0 {
 					b = append(b, Blocks{Pos: []int32{int32(x), int32(y), int32(z)}, State: s - 1})
+					if m, ok := p.blockEntities[Vec3D{int32(x), int32(y), int32(z)}]; ok {
+						b[len(b)-1].Nbt = &m
+					}
 				}
 			}
 		}
 	}
+	palette := p.paletteSnapshot()[1:]
+	p.mu.RUnlock()
+	entities, err := p.nbtEntities()
 	return &Nbt{
 		Blocks:      b,
-		Entities:    p.entity.entity,
-		Palette:     p.Palette()[1:],
+		Entities:    entities,
+		Palette:     palette,
 		Size:        []int32{p.regionSize.X, p.regionSize.Y, p.regionSize.Z},
 		Author:      p.MetaData.Author,
-		DataVersion: int32(defaultMinecraftDataVersion),
+		DataVersion: p.MinecraftDataVersion,
+		err:         err,
 	}
 }
 
@@ -264,30 +356,18 @@ func (p *blockStatePalette) id(block BlockState) int {
 }
 
 func (p *blockStatePalette) value(index int) BlockState {
-	p.Lock()
-	defer p.Unlock()
+	p.RLock()
+	defer p.RUnlock()
 	return p.palette[index]
 }
 
 func (p *blockStatePalette) contain(block BlockState) bool {
-	p.Lock()
-	defer p.Unlock()
+	p.RLock()
+	defer p.RUnlock()
 	_, ok := p.paletteMap[block]
 	return ok
 }
 
-func (p *blockStatePalette) changeMaterial(from, to BlockState) {
-	p.Lock()
-	defer p.Unlock()
-	index, ok := p.paletteMap[from]
-	if !ok {
-		return
-	}
-	p.palette[index] = to
-	delete(p.paletteMap, from)
-	p.paletteMap[to] = index
-}
-
 type entityContainer struct {
 	entity []Entity
 }
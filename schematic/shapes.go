@@ -0,0 +1,333 @@
+package schematic
+
+import "strings"
+
+// sides are the horizontal directions in the order of their properties.
+var sides = []string{"north", "east", "south", "west"}
+
+func opposite(side string) string {
+	return rotateFacing(rotateFacing(side, true), true)
+}
+
+// UpdateShapes recomputes the connection properties of the blocks in box from
+// their neighbours, the way the game does when a block is placed next to
+// them: fences, walls, glass panes, iron bars and fence gates in walls,
+// redstone wire, the shape of stairs, double chests, tripwire and the faces
+// of vines. It returns the number of blocks that changed.
+//
+// A vine left without any support keeps its faces.
+func (p *Project) UpdateShapes(box Box) int {
+	type change struct {
+		x, y, z int
+		s       BlockState
+	}
+	var changes []change
+	var chests []Vec3D
+	at := func(x, y, z int) BlockState { return p.GetBlock(x, y, z) }
+	p.ForEachInBox(box, func(x, y, z int, s BlockState) {
+		name := shortName(s)
+		if name == "chest" || name == "trapped_chest" {
+			chests = append(chests, Vec3D{int32(x), int32(y), int32(z)})
+			return
+		}
+		props := shapeProperties(at, x, y, z, s)
+		if len(props) == 0 {
+			return
+		}
+		if n := NewBlockState(withProperties(s.Properties, props)); n != s {
+			changes = append(changes, change{x, y, z, n})
+		}
+	})
+	// every shape is computed from the blocks as they were
+	for _, c := range changes {
+		p.SetBlock(c.x, c.y, c.z, c.s.Properties)
+	}
+	return len(changes) + p.pairChests(box, chests)
+}
+
+// shapeProperties returns the connection properties of the block s at x, y,
+// z, nil for blocks without any.
+func shapeProperties(at func(x, y, z int) BlockState, x, y, z int, s BlockState) map[string]string {
+	name := shortName(s)
+	neighbour := func(side string) BlockState {
+		o := facingOffset(side)
+		return at(x+int(o.X), y+int(o.Y), z+int(o.Z))
+	}
+	props := make(map[string]string)
+	switch {
+	case strings.HasSuffix(name, "_fence"):
+		for _, side := range sides {
+			n := neighbour(side)
+			nn := shortName(n)
+			c := sturdy(n) || strings.HasSuffix(nn, "_fence") && (nn == "nether_brick_fence") == (name == "nether_brick_fence") ||
+				gateConnects(n, side)
+			props[side] = boolString(c)
+		}
+	case name == "iron_bars" || strings.HasSuffix(name, "_pane"):
+		for _, side := range sides {
+			n := neighbour(side)
+			nn := shortName(n)
+			props[side] = boolString(sturdy(n) || nn == "iron_bars" || strings.HasSuffix(nn, "_pane") || strings.HasSuffix(nn, "_wall"))
+		}
+	case strings.HasSuffix(name, "_wall") && !strings.HasSuffix(name, "_sign") && !strings.HasSuffix(name, "_banner"):
+		above := at(x, y+1, z)
+		connected := make(map[string]bool)
+		for _, side := range sides {
+			n := neighbour(side)
+			nn := shortName(n)
+			connected[side] = sturdy(n) || strings.HasSuffix(nn, "_wall") || nn == "iron_bars" || strings.HasSuffix(nn, "_pane") ||
+				gateConnects(n, side)
+			switch {
+			case !connected[side]:
+				props[side] = "none"
+			case sturdy(above) || strings.HasSuffix(shortName(above), "_wall") && property(above.Properties, side) != "none":
+				props[side] = "tall"
+			default:
+				props[side] = "low"
+			}
+		}
+		// the game raises a post under a wall post, at ends, corners and
+		// lone walls, never between two tall sides, and otherwise only for
+		// blocks above standing on it
+		aboveName := shortName(above)
+		var post bool
+		switch {
+		case strings.HasSuffix(aboveName, "_wall") && property(above.Properties, "up") == "true":
+			post = true
+		case !connected["north"] && !connected["south"] && !connected["east"] && !connected["west"],
+			connected["north"] != connected["south"], connected["east"] != connected["west"]:
+			post = true
+		case props["north"] == "tall" && props["south"] == "tall", props["east"] == "tall" && props["west"] == "tall":
+			post = false
+		default:
+			post = sturdy(above) || strings.HasSuffix(aboveName, "_wall") || strings.HasSuffix(aboveName, "_fence") ||
+				strings.Contains(aboveName, "torch") || strings.Contains(aboveName, "lantern") ||
+				strings.HasSuffix(aboveName, "_sign") || strings.HasSuffix(aboveName, "_banner") ||
+				strings.HasSuffix(aboveName, "_pressure_plate")
+		}
+		props["up"] = boolString(post)
+	case strings.HasSuffix(name, "_fence_gate"):
+		f := property(s.Properties, "facing")
+		l, r := neighbour(rotateFacing(f, true)), neighbour(rotateFacing(f, false))
+		props["in_wall"] = boolString(strings.HasSuffix(shortName(l), "_wall") || strings.HasSuffix(shortName(r), "_wall"))
+	case strings.HasSuffix(name, "_stairs"):
+		props["shape"] = stairsShape(neighbour, s)
+	case name == "redstone_wire":
+		return wireSides(at, x, y, z, s)
+	case name == "tripwire":
+		for _, side := range sides {
+			n := neighbour(side)
+			props[side] = boolString(shortName(n) == "tripwire" || shortName(n) == "tripwire_hook" && property(n.Properties, "facing") == opposite(side))
+		}
+	case name == "vine":
+		above := at(x, y+1, z)
+		kept := 0
+		for _, side := range sides {
+			if property(s.Properties, side) != "true" {
+				continue
+			}
+			// a face hangs from the same face of a vine above
+			c := sturdy(neighbour(side)) || shortName(above) == "vine" && property(above.Properties, side) == "true"
+			props[side] = boolString(c)
+			if c {
+				kept++
+			}
+		}
+		props["up"] = boolString(sturdy(above))
+		if kept == 0 && props["up"] == "false" {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return props
+}
+
+// sturdy reports whether fences, walls and panes connect to the side of b,
+// full blocks except leaves and a few others.
+func sturdy(b BlockState) bool {
+	if b.Properties == nil {
+		return false
+	}
+	name := shortName(b)
+	switch name {
+	case "barrier", "pumpkin", "carved_pumpkin", "jack_o_lantern", "melon":
+		return false
+	}
+	if strings.HasSuffix(name, "_leaves") || strings.HasSuffix(name, "shulker_box") || isFluid(name) {
+		return false
+	}
+	return isFullBlock(b.Properties)
+}
+
+// gateConnects reports whether the fence gate b connects to a fence or wall
+// on its side, which happens when the gate is across the direction.
+func gateConnects(b BlockState, side string) bool {
+	if !strings.HasSuffix(shortName(b), "_fence_gate") {
+		return false
+	}
+	f := property(b.Properties, "facing")
+	return f != side && f != opposite(side)
+}
+
+func boolString(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+// stairsShape returns the shape of the stairs s, corners form with stairs
+// of the same half in front of or behind them.
+func stairsShape(neighbour func(side string) BlockState, s BlockState) string {
+	facing, half := property(s.Properties, "facing"), property(s.Properties, "half")
+	isStairs := func(b BlockState) bool {
+		return strings.HasSuffix(shortName(b), "_stairs") && property(b.Properties, "half") == half
+	}
+	// the neighbour on the side isn't stairs facing the same way
+	canTake := func(side string) bool {
+		n := neighbour(side)
+		return !isStairs(n) || property(n.Properties, "facing") != facing
+	}
+	axis := func(f string) bool { return f == "north" || f == "south" }
+	if front := neighbour(facing); isStairs(front) {
+		f := property(front.Properties, "facing")
+		if axis(f) != axis(facing) && canTake(opposite(f)) {
+			if f == rotateFacing(facing, false) {
+				return "outer_left"
+			}
+			return "outer_right"
+		}
+	}
+	if back := neighbour(opposite(facing)); isStairs(back) {
+		f := property(back.Properties, "facing")
+		if axis(f) != axis(facing) && canTake(f) {
+			if f == rotateFacing(facing, false) {
+				return "inner_left"
+			}
+			return "inner_right"
+		}
+	}
+	return "straight"
+}
+
+// redstoneComponents connect to redstone wire on every side.
+var redstoneComponents = map[string]bool{
+	"redstone_wire": true, "redstone_block": true, "redstone_torch": true, "redstone_wall_torch": true,
+	"lever": true, "target": true, "daylight_detector": true, "trapped_chest": true,
+	"comparator": true, "tripwire_hook": true, "sculk_sensor": true, "calibrated_sculk_sensor": true,
+}
+
+// wireSides returns the sides of the redstone wire at x, y, z.
+func wireSides(at func(x, y, z int) BlockState, x, y, z int, s BlockState) map[string]string {
+	props := make(map[string]string)
+	dot := true
+	for _, side := range sides {
+		if p := property(s.Properties, side); p != "" && p != "none" {
+			dot = false
+		}
+	}
+	covered := sturdy(at(x, y+1, z))
+	count := 0
+	for _, side := range sides {
+		o := facingOffset(side)
+		nx, nz := x+int(o.X), z+int(o.Z)
+		n := at(nx, y, nz)
+		nn := shortName(n)
+		v := "none"
+		switch {
+		case redstoneComponents[nn] || strings.HasSuffix(nn, "_button") || strings.HasSuffix(nn, "_pressure_plate"):
+			v = "side"
+		case nn == "repeater":
+			if f := property(n.Properties, "facing"); f == side || f == opposite(side) {
+				v = "side"
+			}
+		case nn == "observer":
+			if property(n.Properties, "facing") == side {
+				v = "side"
+			}
+		case !covered && sturdy(n) && shortName(at(nx, y+1, nz)) == "redstone_wire":
+			// the wire climbs the block on this side
+			v = "up"
+		case !sturdy(n) && shortName(at(nx, y-1, nz)) == "redstone_wire":
+			v = "side"
+		}
+		props[side] = v
+		if v != "none" {
+			count++
+		}
+	}
+	// a wire with no connection stays a dot if it was one, otherwise it is
+	// drawn across the block on each axis it doesn't connect along, both
+	// axes looked at before either is drawn, so a lone wire is a cross
+	if count == 0 && dot {
+		return props
+	}
+	nsNone := props["north"] == "none" && props["south"] == "none"
+	ewNone := props["east"] == "none" && props["west"] == "none"
+	for _, side := range sides {
+		if props[side] != "none" {
+			continue
+		}
+		if (side == "east" || side == "west") && nsNone || (side == "north" || side == "south") && ewNone {
+			props[side] = "side"
+		}
+	}
+	return props
+}
+
+// pairChests makes double chests of the chests at the positions, keeping the
+// pairs that are already right, and returns the number of chests changed.
+func (p *Project) pairChests(box Box, chests []Vec3D) int {
+	chestAt := make(map[Vec3D]BlockState, len(chests))
+	for _, v := range chests {
+		chestAt[v] = p.GetBlock(int(v.X), int(v.Y), int(v.Z))
+	}
+	// partner returns the position of the other half for the type t
+	partner := func(v Vec3D, s BlockState, t string) Vec3D {
+		o := facingOffset(rotateFacing(property(s.Properties, "facing"), t == "left"))
+		return Vec3D{v.X + o.X, v.Y + o.Y, v.Z + o.Z}
+	}
+	matches := func(a, b BlockState) bool {
+		return a.Name == b.Name && property(a.Properties, "facing") == property(b.Properties, "facing")
+	}
+	types := make(map[Vec3D]string, len(chests))
+	for _, v := range chests {
+		s := chestAt[v]
+		t := property(s.Properties, "type")
+		if t == "single" {
+			continue
+		}
+		pv := partner(v, s, t)
+		n := p.GetBlock(int(pv.X), int(pv.Y), int(pv.Z))
+		want := map[string]string{"left": "right", "right": "left"}[t]
+		if matches(s, n) && property(n.Properties, "type") == want {
+			types[v] = t
+		}
+	}
+	for _, v := range chests {
+		if types[v] != "" {
+			continue
+		}
+		s := chestAt[v]
+		types[v] = "single"
+		for _, t := range []string{"left", "right"} {
+			pv := partner(v, s, t)
+			n, ok := chestAt[pv]
+			if ok && box.Contains(int(pv.X), int(pv.Y), int(pv.Z)) && types[pv] == "" && matches(s, n) {
+				types[v] = t
+				types[pv] = map[string]string{"left": "right", "right": "left"}[t]
+				break
+			}
+		}
+	}
+	changed := 0
+	for _, v := range chests {
+		s := chestAt[v]
+		if n := NewBlockState(withProperties(s.Properties, map[string]string{"type": types[v]})); n != s {
+			p.SetBlock(int(v.X), int(v.Y), int(v.Z), n.Properties)
+			changed++
+		}
+	}
+	return changed
+}
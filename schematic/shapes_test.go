@@ -0,0 +1,89 @@
+package schematic
+
+import (
+	"github.com/Tnze/go-mc/level/block"
+	"testing"
+)
+
+func TestUpdateShapes(t *testing.T) {
+	with := func(b block.Block, props map[string]string) block.Block { return withProperties(b, props) }
+	p := NewProject("shapes", 8, 3, 8)
+	// a fence running east into stone, next to a nether brick fence
+	p.SetBlock(0, 0, 0, block.OakFence{})
+	p.SetBlock(1, 0, 0, block.OakFence{})
+	p.SetBlock(2, 0, 0, block.Stone{})
+	p.SetBlock(0, 0, 1, block.NetherBrickFence{})
+	// a straight wall with a full block on one end
+	p.SetBlock(0, 0, 3, block.CobblestoneWall{})
+	p.SetBlock(1, 0, 3, block.CobblestoneWall{})
+	p.SetBlock(2, 0, 3, block.CobblestoneWall{})
+	p.SetBlock(1, 1, 3, block.Stone{})
+	// a crossing of low walls has no post
+	for _, v := range [][2]int{{6, 4}, {5, 4}, {7, 4}, {6, 3}, {6, 5}} {
+		p.SetBlock(v[0], 0, v[1], block.CobblestoneWall{})
+	}
+	p.SetBlock(4, 0, 0, block.GlassPane{})
+	p.SetBlock(5, 0, 0, block.IronBars{})
+	// an outer corner of stairs
+	p.SetBlock(4, 0, 3, with(block.OakStairs{}, map[string]string{"facing": "north"}))
+	p.SetBlock(4, 0, 2, with(block.OakStairs{}, map[string]string{"facing": "east"}))
+	p.SetBlock(6, 0, 6, with(block.Chest{}, map[string]string{"facing": "north"}))
+	p.SetBlock(7, 0, 6, with(block.Chest{}, map[string]string{"facing": "north"}))
+	p.SetBlock(0, 0, 6, block.RedstoneWire{})
+	p.SetBlock(1, 0, 6, block.RedstoneWire{})
+	p.SetBlock(2, 0, 6, block.RedstoneWire{})
+	p.SetBlock(2, 0, 7, block.Stone{})
+	p.SetBlock(2, 1, 7, block.RedstoneWire{})
+	// a lone wire that isn't a dot becomes a cross
+	p.SetBlock(3, 2, 1, with(block.RedstoneWire{}, map[string]string{"north": "side", "south": "side"}))
+	p.SetBlock(4, 0, 5, with(block.TripwireHook{}, map[string]string{"facing": "south"}))
+	p.SetBlock(4, 0, 6, block.Tripwire{})
+	p.SetBlock(6, 1, 2, with(block.Vine{}, map[string]string{"north": "true", "south": "true"}))
+	p.SetBlock(6, 1, 1, block.Stone{})
+
+	if n := p.UpdateShapes(p.Bounds()); n == 0 {
+		t.Fatalf("Error, no block changed")
+	}
+	for _, c := range []struct {
+		pos       Vec3D
+		name, val string
+	}{
+		{Vec3D{0, 0, 0}, "east", "true"},
+		{Vec3D{0, 0, 0}, "south", "false"},
+		{Vec3D{1, 0, 0}, "west", "true"},
+		{Vec3D{1, 0, 0}, "east", "true"},
+		{Vec3D{0, 0, 1}, "north", "false"},
+		{Vec3D{1, 0, 3}, "east", "tall"},
+		{Vec3D{1, 0, 3}, "up", "false"},
+		{Vec3D{0, 0, 3}, "east", "low"},
+		{Vec3D{0, 0, 3}, "up", "true"},
+		{Vec3D{6, 0, 4}, "up", "false"},
+		{Vec3D{6, 0, 4}, "north", "low"},
+		{Vec3D{6, 0, 4}, "west", "low"},
+		{Vec3D{5, 0, 4}, "up", "true"},
+		{Vec3D{4, 0, 0}, "east", "true"},
+		{Vec3D{5, 0, 0}, "west", "true"},
+		{Vec3D{4, 0, 3}, "shape", "outer_right"},
+		{Vec3D{6, 0, 6}, "type", "left"},
+		{Vec3D{7, 0, 6}, "type", "right"},
+		{Vec3D{1, 0, 6}, "west", "side"},
+		{Vec3D{1, 0, 6}, "north", "none"},
+		{Vec3D{0, 0, 6}, "west", "side"},
+		{Vec3D{2, 0, 6}, "south", "up"},
+		{Vec3D{3, 2, 1}, "north", "side"},
+		{Vec3D{3, 2, 1}, "east", "side"},
+		{Vec3D{3, 2, 1}, "south", "side"},
+		{Vec3D{3, 2, 1}, "west", "side"},
+		{Vec3D{4, 0, 6}, "north", "true"},
+		{Vec3D{6, 1, 2}, "north", "true"},
+		{Vec3D{6, 1, 2}, "south", "false"},
+	} {
+		b := p.GetBlock(int(c.pos.X), int(c.pos.Y), int(c.pos.Z))
+		if v := property(b.Properties, c.name); v != c.val {
+			t.Fatalf("Error, %s %s is %q, want %q", b.Name, c.name, v, c.val)
+		}
+	}
+	if n := p.UpdateShapes(p.Bounds()); n != 0 {
+		t.Fatalf("Error, %d blocks changed again", n)
+	}
+}
@@ -0,0 +1,201 @@
+package schematic
+
+import (
+	"math/bits"
+)
+
+const sectionSize = 16
+
+// section is a 16³ cube of blocks with its own palette of indices into the
+// project palette, so it usually needs only a few bits per block.
+type section struct {
+	data    *BitArray
+	palette []int
+}
+
+func (s *section) get(i int) int {
+	return s.palette[s.data.getBlock(int64(i))]
+}
+
+func (s *section) set(i, id int) {
+	for l, g := range s.palette {
+		if g == id {
+			s.data.setBlock(int64(i), l)
+			return
+		}
+	}
+	s.palette = append(s.palette, id)
+	s.data.setBlock(int64(i), len(s.palette)-1)
+}
+
+// sparseStorage splits the project in sections allocated when a block is
+// first set in them. The blocks of missing sections are the palette index
+// fill, air unless ChangeMaterial replaced it.
+type sparseStorage struct {
+	size     Vec3D
+	nx, nz   int
+	sections []*section
+	fill     int
+}
+
+func newSparseStorage(size Vec3D) *sparseStorage {
+	n := func(l int32) int { return (int(l) + sectionSize - 1) / sectionSize }
+	return &sparseStorage{
+		size:     size,
+		nx:       n(size.X),
+		nz:       n(size.Z),
+		sections: make([]*section, n(size.X)*n(size.Y)*n(size.Z)),
+	}
+}
+
+// locate returns the section of x, y, z and the index of the block in it.
+func (s *sparseStorage) locate(x, y, z int) (int, int) {
+	return (y/sectionSize*s.nz+z/sectionSize)*s.nx + x/sectionSize,
+		(y%sectionSize*sectionSize+z%sectionSize)*sectionSize + x%sectionSize
+}
+
+// origin returns the position of the first block of section k and the size of
+// the part of the section inside the project.
+func (s *sparseStorage) origin(k int) (x, y, z, w, h, d int) {
+	x, z, y = k%s.nx*sectionSize, k/s.nx%s.nz*sectionSize, k/(s.nx*s.nz)*sectionSize
+	w = minInt(sectionSize, int(s.size.X)-x)
+	h = minInt(sectionSize, int(s.size.Y)-y)
+	d = minInt(sectionSize, int(s.size.Z)-z)
+	return
+}
+
+func (s *sparseStorage) get(x, y, z int) int {
+	k, i := s.locate(x, y, z)
+	if s.sections[k] == nil {
+		return s.fill
+	}
+	return s.sections[k].get(i)
+}
+
+func (s *sparseStorage) set(x, y, z, id int) {
+	k, i := s.locate(x, y, z)
+	if s.sections[k] == nil {
+		if id == s.fill {
+			return
+		}
+		s.sections[k] = &section{NewEmptyBitArray(sectionSize * sectionSize * sectionSize), []int{s.fill}}
+	}
+	s.sections[k].set(i, id)
+}
+
+func (s *sparseStorage) row(x, y, z int, dst []int) {
+	for len(dst) > 0 {
+		k, i := s.locate(x, y, z)
+		n := minInt(len(dst), sectionSize-x%sectionSize)
+		if sec := s.sections[k]; sec == nil {
+			for j := range dst[:n] {
+				dst[j] = s.fill
+			}
+		} else {
+			r := bitReader{b: sec.data}
+			r.seek(i)
+			for j := range dst[:n] {
+				dst[j] = sec.palette[r.next()]
+			}
+		}
+		dst, x = dst[n:], x+n
+	}
+}
+
+// each calls fn with the index in the section of every block of section k
+// inside the project.
+func (s *sparseStorage) each(k int, fn func(i int)) {
+	_, _, _, w, h, d := s.origin(k)
+	for y := 0; y < h; y++ {
+		for z := 0; z < d; z++ {
+			for x := 0; x < w; x++ {
+				fn((y*sectionSize+z)*sectionSize + x)
+			}
+		}
+	}
+}
+
+func (s *sparseStorage) count(n int) []int {
+	c := make([]int, n)
+	for k, sec := range s.sections {
+		if sec == nil {
+			_, _, _, w, h, d := s.origin(k)
+			c[s.fill] += w * h * d
+			continue
+		}
+		s.each(k, func(i int) {
+			c[sec.get(i)]++
+		})
+	}
+	return c
+}
+
+// remap also compacts the sections, those left with only fill are freed.
+func (s *sparseStorage) remap(m []int, n int) {
+	s.fill = m[s.fill]
+	for k, sec := range s.sections {
+		if sec == nil {
+			continue
+		}
+		used := make([]bool, len(sec.palette))
+		s.each(k, func(i int) {
+			used[sec.data.getBlock(int64(i))] = true
+		})
+		local := make([]int, len(sec.palette))
+		var palette []int
+		for l, g := range sec.palette {
+			if !used[l] {
+				continue
+			}
+			g = m[g]
+			local[l] = len(palette)
+			for j, e := range palette {
+				if e == g {
+					local[l] = j
+				}
+			}
+			if local[l] == len(palette) {
+				palette = append(palette, g)
+			}
+		}
+		if len(palette) == 1 && palette[0] == s.fill {
+			s.sections[k] = nil
+			continue
+		}
+		b := NewBitArray(bits.Len(uint(len(palette)-1)), sectionSize*sectionSize*sectionSize, nil)
+		s.each(k, func(i int) {
+			b.setAt(int64(i), local[sec.data.getBlock(int64(i))])
+		})
+		s.sections[k] = &section{b, palette}
+	}
+}
+
+func (s *sparseStorage) dense() *BitArray {
+	n := s.fill
+	for _, sec := range s.sections {
+		if sec != nil {
+			for _, g := range sec.palette {
+				n = max(n, g)
+			}
+		}
+	}
+	b := NewBitArray(bits.Len(uint(n)), int(s.size.X)*int(s.size.Y)*int(s.size.Z), nil)
+	for k, sec := range s.sections {
+		if sec == nil && s.fill == 0 {
+			continue
+		}
+		ox, oy, oz, w, h, d := s.origin(k)
+		for y := 0; y < h; y++ {
+			for z := 0; z < d; z++ {
+				for x := 0; x < w; x++ {
+					id := s.fill
+					if sec != nil {
+						id = sec.get((y*sectionSize+z)*sectionSize + x)
+					}
+					b.setAt(int64(s.size.getIndex(ox+x, oy+y, oz+z)), id)
+				}
+			}
+		}
+	}
+	return b
+}
@@ -0,0 +1,89 @@
+package schematic
+
+import (
+	"github.com/Tnze/go-mc/level/block"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestSparseProject(t *testing.T) {
+	dense, sparse := NewProject("dense", 37, 20, 33), NewSparseProject("sparse", 37, 20, 33)
+	blocks := []block.Block{block.Air{}, block.Stone{}, block.Dirt{}, block.Glass{}, block.OakLog{Axis: block.X}}
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 3000; i++ {
+		x, y, z, b := r.Intn(37), r.Intn(20), r.Intn(33), blocks[r.Intn(len(blocks))]
+		dense.SetBlock(x, y, z, b)
+		sparse.SetBlock(x, y, z, b)
+	}
+	same := func(step string) {
+		if dense.MetaData.TotalBlocks != sparse.MetaData.TotalBlocks {
+			t.Fatalf("Error, %s: total blocks %d, sparse %d", step, dense.MetaData.TotalBlocks, sparse.MetaData.TotalBlocks)
+		}
+		dense.ForEachBlock(func(x, y, z int, s BlockState) {
+			if sparse.GetBlock(x, y, z) != s {
+				t.Fatalf("Error, %s: block at %d, %d, %d: %v, sparse %v", step, x, y, z, s, sparse.GetBlock(x, y, z))
+			}
+		})
+		dense.Compact()
+		sparse.Compact()
+		if !reflect.DeepEqual(dense.Palette(), sparse.Palette()) || !reflect.DeepEqual(dense.Data(), sparse.Data()) {
+			t.Fatalf("Error, %s: dense layout differs", step)
+		}
+	}
+	same("set")
+
+	dense.ChangeMaterial(block.Air{}, block.Glass{})
+	sparse.ChangeMaterial(block.Air{}, block.Glass{})
+	same("air to glass")
+
+	dense.Fill(dense.Bounds(), Single(block.Air{}), nil)
+	sparse.Fill(sparse.Bounds(), Single(block.Air{}), nil)
+	same("clear")
+	for _, s := range sparse.blocks.(*sparseStorage).sections {
+		if s != nil {
+			t.Fatalf("Error, empty section not freed")
+		}
+	}
+}
+
+// wall sets the outline of a city wall, 8 blocks high and 2 thick, which is
+// only a few percent of the project.
+func wall(p *Project) {
+	for y := 0; y < 8; y++ {
+		for i := 0; i < p.XRange(); i++ {
+			for d := 0; d < 2; d++ {
+				p.SetBlock(i, y, d, block.StoneBricks{})
+				p.SetBlock(i, y, p.ZRange()-1-d, block.StoneBricks{})
+				p.SetBlock(d, y, i, block.StoneBricks{})
+				p.SetBlock(p.XRange()-1-d, y, i, block.StoneBricks{})
+			}
+		}
+	}
+}
+
+func BenchmarkStorage(b *testing.B) {
+	for _, c := range []struct {
+		name string
+		new  func(name string, x, y, z int, opts ...Option) *Project
+	}{{"Dense", NewProject}, {"Sparse", NewSparseProject}} {
+		b.Run(c.name+"/Wall", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				wall(c.new("wall", 1024, 256, 1024))
+			}
+		})
+		p := c.new("wall", 1024, 256, 1024)
+		wall(p)
+		b.Run(c.name+"/ForEachNonAir", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				p.ForEachNonAir(func(x, y, z int, s BlockState) {})
+			}
+		})
+		b.Run(c.name+"/GetBlock", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = p.GetBlock(i%1024, i%8, 1)
+			}
+		})
+	}
+}
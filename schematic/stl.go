@@ -0,0 +1,86 @@
+package schematic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// ReadSTL parses a binary or ASCII STL file.
+func ReadSTL(r io.Reader) (*Mesh, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if isBinarySTL(data) {
+		return readBinarySTL(data)
+	}
+	return readASCIISTL(data)
+}
+
+// isBinarySTL reports whether data is a binary STL. Some exporters start binary
+// headers with "solid" too, so the size of the file decides first.
+func isBinarySTL(data []byte) bool {
+	if len(data) >= 84 {
+		n := binary.LittleEndian.Uint32(data[80:84])
+		if uint64(len(data)) == 84+50*uint64(n) {
+			return true
+		}
+	}
+	return !bytes.HasPrefix(bytes.TrimSpace(data), []byte("solid"))
+}
+
+func readBinarySTL(data []byte) (*Mesh, error) {
+	if len(data) < 84 {
+		return nil, fmt.Errorf("stl: file too short")
+	}
+	n := int(binary.LittleEndian.Uint32(data[80:84]))
+	if len(data) < 84+50*n {
+		return nil, fmt.Errorf("stl: expected %d triangles, file is truncated", n)
+	}
+	m := &Mesh{Triangles: make([]Triangle, n)}
+	for i := 0; i < n; i++ {
+		// skip the 12 bytes normal, the attribute byte count is ignored
+		off := 84 + 50*i + 12
+		for v := 0; v < 3; v++ {
+			m.Triangles[i].V[v] = Vec3{
+				X: float64(math.Float32frombits(binary.LittleEndian.Uint32(data[off:]))),
+				Y: float64(math.Float32frombits(binary.LittleEndian.Uint32(data[off+4:]))),
+				Z: float64(math.Float32frombits(binary.LittleEndian.Uint32(data[off+8:]))),
+			}
+			off += 12
+		}
+	}
+	return m, nil
+}
+
+func readASCIISTL(data []byte) (*Mesh, error) {
+	var (
+		m     = &Mesh{}
+		verts []Vec3
+	)
+	for i, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "vertex":
+			v, err := parseVec3(fields[1:])
+			if err != nil {
+				return nil, fmt.Errorf("stl line %d: %w", i+1, err)
+			}
+			verts = append(verts, v)
+		case "endfacet":
+			if len(verts) != 3 {
+				return nil, fmt.Errorf("stl line %d: facet with %d vertices", i+1, len(verts))
+			}
+			m.Triangles = append(m.Triangles, Triangle{V: [3]Vec3{verts[0], verts[1], verts[2]}})
+			verts = verts[:0]
+		}
+	}
+	return m, nil
+}
@@ -112,3 +112,73 @@ func (b *BitArray) resize(bits, EntrySize int) BitArray {
 	}
 	return *n
 }
+
+// blockStorage keeps the palette index of every block of a Project.
+type blockStorage interface {
+	get(x, y, z int) int
+	set(x, y, z, id int)
+
+	//row reads the indices of len(dst) blocks from x, y, z along x
+	row(x, y, z int, dst []int)
+
+	//count returns how many blocks use each of the n palette indices
+	count(n int) []int
+
+	//remap replaces every palette index i with m[i], the new palette has n
+	//entries
+	remap(m []int, n int)
+
+	//dense returns the blocks in the Litematica layout
+	dense() *BitArray
+}
+
+// denseStorage is a BitArray of every block of the project, the layout
+// Litematica uses.
+type denseStorage struct {
+	size Vec3D
+	*BitArray
+}
+
+func newDenseStorage(size Vec3D) *denseStorage {
+	return &denseStorage{size, NewEmptyBitArray(int(size.X) * int(size.Y) * int(size.Z))}
+}
+
+func (d *denseStorage) get(x, y, z int) int {
+	return d.getBlock(int64(d.size.getIndex(x, y, z)))
+}
+
+func (d *denseStorage) set(x, y, z, id int) {
+	d.setBlock(int64(d.size.getIndex(x, y, z)), id)
+}
+
+func (d *denseStorage) row(x, y, z int, dst []int) {
+	r := bitReader{b: d.BitArray}
+	r.seek(d.size.getIndex(x, y, z))
+	for i := range dst {
+		dst[i] = r.next()
+	}
+}
+
+func (d *denseStorage) count(n int) []int {
+	c := make([]int, n)
+	r := bitReader{b: d.BitArray}
+	r.seek(0)
+	for i := 0; i < d.entrySize; i++ {
+		c[r.next()]++
+	}
+	return c
+}
+
+func (d *denseStorage) remap(m []int, n int) {
+	b := NewBitArray(bits.Len(uint(n-1)), d.entrySize, nil)
+	r := bitReader{b: d.BitArray}
+	r.seek(0)
+	for i := 0; i < d.entrySize; i++ {
+		b.setAt(int64(i), m[r.next()])
+	}
+	*d.BitArray = *b
+}
+
+func (d *denseStorage) dense() *BitArray {
+	return d.BitArray
+}
@@ -0,0 +1,114 @@
+package schematic
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// StructureMaxSize is the largest size of a structure block along each axis.
+const StructureMaxSize = 48
+
+// StructureTile is a part of a project small enough for a structure block.
+type StructureTile struct {
+	//Name The prefix and grid coordinates, e.g. castle_1_0_2
+	Name string
+
+	//Grid Coordinates of the tile in the grid of tiles
+	Grid Vec3D
+
+	//Offset Position of the tile's corner in the project
+	Offset Vec3D
+
+	Structure *Nbt
+}
+
+// StructureTiles splits the project into a grid of structures of at most
+// StructureMaxSize blocks along each axis. Tiles without blocks or entities
+// are left out.
+func (p *Project) StructureTiles(prefix string) []StructureTile {
+	var tiles []StructureTile
+	size := p.Size()
+	for gy := int32(0); gy*StructureMaxSize < size.Y; gy++ {
+		for gz := int32(0); gz*StructureMaxSize < size.Z; gz++ {
+			for gx := int32(0); gx*StructureMaxSize < size.X; gx++ {
+				o := Vec3D{gx * StructureMaxSize, gy * StructureMaxSize, gz * StructureMaxSize}
+				c := p.Crop(Box{o, Vec3D{o.X + StructureMaxSize - 1, o.Y + StructureMaxSize - 1, o.Z + StructureMaxSize - 1}})
+				if c.MetaData.TotalBlocks == 0 && len(c.entity.entity) == 0 {
+					continue
+				}
+				tiles = append(tiles, StructureTile{
+					Name:      fmt.Sprintf("%s_%d_%d_%d", prefix, gx, gy, gz),
+					Grid:      Vec3D{gx, gy, gz},
+					Offset:    o,
+					Structure: c.Nbt(),
+				})
+			}
+		}
+	}
+	return tiles
+}
+
+// WriteStructureTiles writes the tiles of the project to dir as <name>.nbt
+// files.
+func (p *Project) WriteStructureTiles(dir, prefix string) ([]StructureTile, error) {
+	tiles := p.StructureTiles(prefix)
+	w := dirPack(dir)
+	for _, t := range tiles {
+		if err := writePackFile(w, t.Name+".nbt", t.Structure.Encode); err != nil {
+			return nil, err
+		}
+	}
+	return tiles, nil
+}
+
+// TilesFunction returns an mcfunction placing the tiles with their
+// structures at namespace:<name>, the corner of the project at the position
+// the function runs at. It needs Minecraft 1.19 or later.
+func TilesFunction(namespace string, tiles []StructureTile) string {
+	var b strings.Builder
+	for _, t := range tiles {
+		fmt.Fprintf(&b, "place template %s:%s ~%d ~%d ~%d\n", namespace, t.Name, t.Offset.X, t.Offset.Y, t.Offset.Z)
+	}
+	return b.String()
+}
+
+// WriteTilesDatapack writes a data pack to dir with the tiles of the project
+// as structures namespace:<prefix>_x_y_z and the function namespace:<prefix>
+// placing all of them. The function uses /place template, so the data
+// version of the project must be of Minecraft 1.19 or later.
+func (p *Project) WriteTilesDatapack(dir, namespace, prefix string) ([]StructureTile, error) {
+	if err := validResource(namespace, prefix); err != nil {
+		return nil, err
+	}
+	if PackFormat(p.MinecraftDataVersion) < 10 {
+		return nil, fmt.Errorf("place template needs Minecraft 1.19 or later, the data version is %d", p.MinecraftDataVersion)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	format := PackFormat(p.MinecraftDataVersion)
+	structures, functions := packDirs(format)
+	w := dirPack(dir)
+	tiles := p.StructureTiles(prefix)
+	for _, t := range tiles {
+		name := path.Join("data", namespace, structures, t.Name+".nbt")
+		if err := writePackFile(w, name, t.Structure.Encode); err != nil {
+			return nil, err
+		}
+	}
+	name := path.Join("data", namespace, functions, prefix+".mcfunction")
+	err := writePackFile(w, name, func(f io.Writer) error {
+		_, err := io.WriteString(f, TilesFunction(namespace, tiles))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writePackMeta(w, format, p.RegionName); err != nil {
+		return nil, err
+	}
+	return tiles, nil
+}
@@ -0,0 +1,89 @@
+package schematic
+
+import (
+	"github.com/Tnze/go-mc/level/block"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStructureTiles(t *testing.T) {
+	p := NewProject("castle", 100, 50, 10)
+	p.Fill(NewBox(0, 0, 0, 99, 0, 9), Single(block.Stone{}), nil)
+	p.SetBlock(97, 49, 9, block.Chest{})
+	_ = p.SetBlockEntity(97, 49, 9, testChest{ID: "minecraft:chest"})
+
+	tiles := p.StructureTiles("castle")
+	// the layer y=0 spans three tiles, the chest is in the fourth
+	if len(tiles) != 4 {
+		t.Fatalf("Error, %d tiles, want 4", len(tiles))
+	}
+	if tiles[3].Name != "castle_2_1_0" || tiles[3].Offset != (Vec3D{96, 48, 0}) {
+		t.Fatalf("Error, tile %s at %v", tiles[3].Name, tiles[3].Offset)
+	}
+	for _, tile := range tiles {
+		for _, s := range tile.Structure.Size {
+			if s > StructureMaxSize {
+				t.Fatalf("Error, tile %s of size %v", tile.Name, tile.Structure.Size)
+			}
+		}
+	}
+	if got := tiles[3].Structure.Size; got[0] != 4 || got[1] != 2 || got[2] != 10 {
+		t.Fatalf("Error, last tile of size %v, want [4 2 10]", got)
+	}
+
+	// putting the tiles back together gives the project
+	q := NewProject("castle", 100, 50, 10)
+	for _, tile := range tiles {
+		c, err := tile.Structure.ProjectWithPalette(tile.Name, 0)
+		if err != nil {
+			t.Fatalf("Error, %v", err)
+		}
+		c.ForEachNonAir(func(x, y, z int, s BlockState) {
+			q.SetBlock(x+int(tile.Offset.X), y+int(tile.Offset.Y), z+int(tile.Offset.Z), s.Properties)
+		})
+		for _, v := range c.BlockEntities() {
+			if v != (Vec3D{1, 1, 9}) || tile.Name != "castle_2_1_0" {
+				t.Fatalf("Error, block entity at %v of %s", v, tile.Name)
+			}
+		}
+	}
+	p.ForEachBlock(func(x, y, z int, s BlockState) {
+		if q.GetBlock(x, y, z) != s {
+			t.Fatalf("Error, block at %d, %d, %d: %v, want %v", x, y, z, q.GetBlock(x, y, z), s)
+		}
+	})
+
+	dir := t.TempDir()
+	if _, err := p.WriteTilesDatapack(dir, "build", "castle"); err == nil {
+		t.Fatalf("Error, datapack for Minecraft 1.18 accepted")
+	}
+	p.MinecraftDataVersion = 3465
+	if _, err := p.WriteTilesDatapack(dir, "build", "castle"); err != nil {
+		t.Fatalf("Error, write datapack: %v", err)
+	}
+	for _, name := range []string{"pack.mcmeta", "data/build/structures/castle_0_0_0.nbt", "data/build/structures/castle_2_1_0.nbt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Fatalf("Error, %v", err)
+		}
+	}
+	function, err := os.ReadFile(filepath.Join(dir, "data/build/functions/castle.mcfunction"))
+	if err != nil {
+		t.Fatalf("Error, %v", err)
+	}
+	if !strings.Contains(string(function), "place template build:castle_2_1_0 ~96 ~48 ~0\n") {
+		t.Fatalf("Error, function:\n%s", function)
+	}
+	if _, err := p.WriteTilesDatapack(dir, "Build", "castle"); err == nil {
+		t.Fatalf("Error, invalid namespace accepted")
+	}
+}
+
+func TestPackFormat(t *testing.T) {
+	for v, want := range map[int32]int{1000: 4, 2586: 6, 3465: 15, 3700: 26, 3953: 48, 4189: 61, 9999: 81} {
+		if got := PackFormat(v); got != want {
+			t.Fatalf("Error, pack format of %d: %d, want %d", v, got, want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package schematic
 
 import (
+	"fmt"
 	"github.com/Tnze/go-mc/level/block"
 	"github.com/Tnze/go-mc/nbt"
 )
@@ -36,35 +37,34 @@ func (s Vec3D) getIndex(x, y, z int) int {
 }
 
 func parseBlocks(states []state) []BlockState {
+	blockPalette, err := parseBlockStates(states)
+	if err != nil {
+		panic(err)
+	}
+	return blockPalette
+}
+
+// parseBlockStates is parseBlocks returning an error for properties that
+// don't decode.
+func parseBlockStates(states []state) ([]BlockState, error) {
 	var blockPalette []BlockState
 	for _, s := range states {
 		b := block.FromID[s.Name]
 		if s.Properties.Type != nbt.TagEnd {
-			err := s.Properties.Unmarshal(&b)
-			if err != nil {
-				panic(err)
+			if err := s.Properties.Unmarshal(&b); err != nil {
+				return nil, fmt.Errorf("block %q: %w", s.Name, err)
 			}
 		}
 		blockPalette = append(blockPalette, BlockState{Name: s.Name, Properties: b})
 	}
-	return blockPalette
+	return blockPalette, nil
 }
 
 func parseEntities(entities []nbt.RawMessage) []Entity {
 	var e []Entity
 	for _, i := range entities {
 		if i.Type != nbt.TagEnd {
-			id := ID{}
-			err := i.Unmarshal(&id)
-			if err != nil {
-				panic(err)
-			}
-			entity := ByID[id.ID]
-			err = i.Unmarshal(&entity)
-			if err != nil {
-				panic(err)
-			}
-			e = append(e, entity)
+			e = append(e, parseEntity(i))
 		}
 	}
 	return e
@@ -76,3 +76,31 @@ func max(a, b int) int {
 	}
 	return b
 }
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func min32(a, b int32) int32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func absInt32(a int32) int32 {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
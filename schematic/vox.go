@@ -0,0 +1,598 @@
+package schematic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/Tnze/go-mc/level/block"
+	"image/color"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxVoxSize is the largest model size MagicaVoxel accepts in each dimension.
+const maxVoxSize = 256
+
+type Vox struct {
+	Version int32
+	Models  []VoxModel
+
+	//Instances place models in the scene, from the nTRN/nGRP/nSHP scene graph
+	Instances []VoxInstance
+
+	//Palette[i] is the colour of colour index i, index 0 is unused
+	Palette [256]color.RGBA
+}
+
+type VoxModel struct {
+	Size   [3]int32
+	Voxels []Voxel
+}
+
+type Voxel struct {
+	X, Y, Z, Color uint8
+}
+
+type VoxInstance struct {
+	Model     int
+	Transform VoxTransform
+}
+
+// VoxTransform maps a voxel at p relative to the centre of its model to
+// Rotation*p + Translation in the scene.
+type VoxTransform struct {
+	Rotation    [3][3]int32
+	Translation [3]int32
+}
+
+var identityVoxTransform = VoxTransform{Rotation: [3][3]int32{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}}
+
+func (t VoxTransform) apply(p [3]int32) [3]int32 {
+	var r [3]int32
+	for i := 0; i < 3; i++ {
+		r[i] = t.Rotation[i][0]*p[0] + t.Rotation[i][1]*p[1] + t.Rotation[i][2]*p[2] + t.Translation[i]
+	}
+	return r
+}
+
+// then returns the transform that applies c first and t after it.
+func (t VoxTransform) then(c VoxTransform) VoxTransform {
+	var r VoxTransform
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				r.Rotation[i][j] += t.Rotation[i][k] * c.Rotation[k][j]
+			}
+		}
+	}
+	r.Translation = t.apply(c.Translation)
+	return r
+}
+
+type VoxOptions struct {
+	//Blocks maps palette colours to blocks
+	Blocks map[color.RGBA]block.Block
+
+	//Colors is used for colours missing from Blocks, default DefaultColorTable
+	Colors ColorTable
+}
+
+type voxNode struct {
+	kind      string
+	children  []int32
+	models    []int32
+	transform VoxTransform
+}
+
+func LoadFromVox(name string, f io.Reader, opt VoxOptions) (*Project, error) {
+	v, err := ReadVoxFile(f)
+	if err != nil {
+		return nil, err
+	}
+	name = filepath.Base(name)
+	return v.toProject(name[:len(name)-len(filepath.Ext(name))], opt)
+}
+
+func ReadVoxFile(r io.Reader) (*Vox, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 8 || string(data[:4]) != "VOX " {
+		return nil, fmt.Errorf("vox: invalid header")
+	}
+	v := &Vox{Version: int32(binary.LittleEndian.Uint32(data[4:8])), Palette: defaultVoxPalette()}
+	nodes := make(map[int32]*voxNode)
+	var size [3]int32
+
+	rd := &voxReader{data: data[8:]}
+	id, content, err := rd.chunk()
+	if err != nil {
+		return nil, err
+	}
+	if id != "MAIN" || len(content) != 0 {
+		return nil, fmt.Errorf("vox: missing MAIN chunk")
+	}
+	for len(rd.data) > 0 && rd.err == nil {
+		id, content, err := rd.chunk()
+		if err != nil {
+			return nil, err
+		}
+		c := &voxReader{data: content}
+		switch id {
+		case "SIZE":
+			size = [3]int32{c.int32(), c.int32(), c.int32()}
+		case "XYZI":
+			n := int(c.int32())
+			if n < 0 {
+				return nil, fmt.Errorf("vox: XYZI chunk has %d voxels", n)
+			}
+			if len(c.data)/4 < n {
+				return nil, fmt.Errorf("vox: XYZI chunk is truncated")
+			}
+			m := VoxModel{Size: size, Voxels: make([]Voxel, n)}
+			for i := range m.Voxels {
+				b := c.data[4*i:]
+				m.Voxels[i] = Voxel{X: b[0], Y: b[1], Z: b[2], Color: b[3]}
+			}
+			v.Models = append(v.Models, m)
+		case "RGBA":
+			for i := 0; i < 255 && 4*i+4 <= len(content); i++ {
+				b := content[4*i:]
+				v.Palette[i+1] = color.RGBA{R: b[0], G: b[1], B: b[2], A: b[3]}
+			}
+		case "nTRN":
+			n := &voxNode{kind: id, transform: identityVoxTransform}
+			nid := c.int32()
+			c.dict()
+			n.children = []int32{c.int32()}
+			c.int32() // reserved id
+			c.int32() // layer id
+			if frames := c.int32(); frames > 0 {
+				n.transform = parseVoxFrame(c.dict())
+			}
+			nodes[nid] = n
+		case "nGRP":
+			n := &voxNode{kind: id}
+			nid := c.int32()
+			c.dict()
+			for i := c.int32(); i > 0 && c.err == nil; i-- {
+				n.children = append(n.children, c.int32())
+			}
+			nodes[nid] = n
+		case "nSHP":
+			n := &voxNode{kind: id}
+			nid := c.int32()
+			c.dict()
+			for i := c.int32(); i > 0 && c.err == nil; i-- {
+				n.models = append(n.models, c.int32())
+				c.dict()
+			}
+			nodes[nid] = n
+		}
+		if c.err != nil {
+			return nil, fmt.Errorf("vox: %s chunk: %w", id, c.err)
+		}
+	}
+	if rd.err != nil {
+		return nil, rd.err
+	}
+
+	if _, ok := nodes[0]; ok {
+		if err := v.walk(nodes, 0, identityVoxTransform, 0); err != nil {
+			return nil, err
+		}
+	} else {
+		for i, m := range v.Models {
+			t := identityVoxTransform
+			t.Translation = [3]int32{m.Size[0] / 2, m.Size[1] / 2, m.Size[2] / 2}
+			v.Instances = append(v.Instances, VoxInstance{Model: i, Transform: t})
+		}
+	}
+	return v, nil
+}
+
+func (v *Vox) walk(nodes map[int32]*voxNode, id int32, t VoxTransform, depth int) error {
+	n, ok := nodes[id]
+	if !ok {
+		return fmt.Errorf("vox: missing scene node %d", id)
+	}
+	if depth > len(nodes) {
+		return fmt.Errorf("vox: scene graph has a cycle")
+	}
+	if n.kind == "nTRN" {
+		t = t.then(n.transform)
+	}
+	for _, m := range n.models {
+		if int(m) >= len(v.Models) || m < 0 {
+			return fmt.Errorf("vox: shape node %d references missing model %d", id, m)
+		}
+		v.Instances = append(v.Instances, VoxInstance{Model: int(m), Transform: t})
+	}
+	for _, c := range n.children {
+		if err := v.walk(nodes, c, t, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseVoxFrame(d map[string]string) VoxTransform {
+	t := identityVoxTransform
+	if s, ok := d["_t"]; ok {
+		f := strings.Fields(s)
+		for i := 0; i < 3 && i < len(f); i++ {
+			n, _ := strconv.Atoi(f[i])
+			t.Translation[i] = int32(n)
+		}
+	}
+	if s, ok := d["_r"]; ok {
+		r, _ := strconv.Atoi(s)
+		i0, i1 := r&3, (r>>2)&3
+		idx := [3]int{i0, i1, 3 - i0 - i1}
+		t.Rotation = [3][3]int32{}
+		for row := 0; row < 3; row++ {
+			sign := int32(1)
+			if r&(1<<(4+row)) != 0 {
+				sign = -1
+			}
+			t.Rotation[row][idx[row]%3] = sign
+		}
+	}
+	return t
+}
+
+type voxReader struct {
+	data []byte
+	err  error
+}
+
+func (r *voxReader) int32() int32 {
+	if len(r.data) < 4 {
+		r.err = io.ErrUnexpectedEOF
+		r.data = nil
+		return 0
+	}
+	n := int32(binary.LittleEndian.Uint32(r.data))
+	r.data = r.data[4:]
+	return n
+}
+
+func (r *voxReader) string() string {
+	n := int(r.int32())
+	if n < 0 || len(r.data) < n {
+		r.err = io.ErrUnexpectedEOF
+		r.data = nil
+		return ""
+	}
+	s := string(r.data[:n])
+	r.data = r.data[n:]
+	return s
+}
+
+func (r *voxReader) dict() map[string]string {
+	d := make(map[string]string)
+	for n := r.int32(); n > 0 && r.err == nil; n-- {
+		k := r.string()
+		d[k] = r.string()
+	}
+	return d
+}
+
+// chunk returns the next chunk. MAIN is the only chunk with children, which are
+// left in the reader so the caller sees them as a flat list.
+func (r *voxReader) chunk() (id string, content []byte, err error) {
+	if len(r.data) < 12 {
+		return "", nil, fmt.Errorf("vox: chunk is truncated")
+	}
+	id = string(r.data[:4])
+	n := int32(binary.LittleEndian.Uint32(r.data[4:]))
+	children := int32(binary.LittleEndian.Uint32(r.data[8:]))
+	if n < 0 || int(n) > len(r.data)-12 {
+		return "", nil, fmt.Errorf("vox: %s chunk is truncated", id)
+	}
+	content = r.data[12 : 12+n]
+	r.data = r.data[12+n:]
+	if id != "MAIN" {
+		if children < 0 || int(children) > len(r.data) {
+			return "", nil, fmt.Errorf("vox: %s chunk is truncated", id)
+		}
+		r.data = r.data[children:]
+	}
+	return id, content, nil
+}
+
+// defaultVoxPalette is the palette MagicaVoxel uses when a file has no RGBA chunk:
+// a 6x6x6 colour cube without black followed by red, green, blue and grey ramps.
+func defaultVoxPalette() [256]color.RGBA {
+	var p [256]color.RGBA
+	i := 1
+	levels := []uint8{0xFF, 0xCC, 0x99, 0x66, 0x33, 0x00}
+	for _, r := range levels {
+		for _, g := range levels {
+			for _, b := range levels {
+				if r|g|b != 0 {
+					p[i] = color.RGBA{R: r, G: g, B: b, A: 0xFF}
+					i++
+				}
+			}
+		}
+	}
+	ramp := []uint8{0xEE, 0xDD, 0xBB, 0xAA, 0x88, 0x77, 0x55, 0x44, 0x22, 0x11}
+	for c := 0; c < 4; c++ {
+		for _, l := range ramp {
+			switch c {
+			case 0:
+				p[i] = color.RGBA{R: l, A: 0xFF}
+			case 1:
+				p[i] = color.RGBA{G: l, A: 0xFF}
+			case 2:
+				p[i] = color.RGBA{B: l, A: 0xFF}
+			case 3:
+				p[i] = color.RGBA{R: l, G: l, B: l, A: 0xFF}
+			}
+			i++
+		}
+	}
+	return p
+}
+
+// toProject places every instance in the scene and converts the result from
+// MagicaVoxel's Z-up coordinates, with Y pointing north, to Minecraft's.
+func (v *Vox) toProject(name string, opt VoxOptions) (*Project, error) {
+	if opt.Colors == nil {
+		opt.Colors = DefaultColorTable
+	}
+	var blocks [256]block.Block
+	for i := 1; i < 256; i++ {
+		if b, ok := opt.Blocks[v.Palette[i]]; ok {
+			blocks[i] = b
+		} else {
+			blocks[i] = opt.Colors.Nearest(v.Palette[i])
+		}
+	}
+
+	type placed struct {
+		pos   [3]int32
+		color uint8
+	}
+	var (
+		voxels []placed
+		lo     = [3]int32{math.MaxInt32, math.MaxInt32, math.MaxInt32}
+		hi     = [3]int32{math.MinInt32, math.MinInt32, math.MinInt32}
+	)
+	for _, in := range v.Instances {
+		m := v.Models[in.Model]
+		for _, vx := range m.Voxels {
+			p := in.Transform.apply([3]int32{
+				int32(vx.X) - m.Size[0]/2,
+				int32(vx.Y) - m.Size[1]/2,
+				int32(vx.Z) - m.Size[2]/2,
+			})
+			for a := 0; a < 3; a++ {
+				lo[a], hi[a] = min32(lo[a], p[a]), max32(hi[a], p[a])
+			}
+			voxels = append(voxels, placed{p, vx.Color})
+		}
+	}
+	if len(voxels) == 0 {
+		return nil, fmt.Errorf("vox: file has no voxels")
+	}
+
+	project := NewProject(name, int(hi[0]-lo[0]+1), int(hi[2]-lo[2]+1), int(hi[1]-lo[1]+1))
+	for _, vx := range voxels {
+		if vx.color == 0 || blocks[vx.color] == nil {
+			continue
+		}
+		project.SetBlock(int(vx.pos[0]-lo[0]), int(vx.pos[2]-lo[2]), int(hi[1]-vx.pos[1]), blocks[vx.color])
+	}
+	return project, nil
+}
+
+// Vox converts the project to a MagicaVoxel file. Block colours come from colors,
+// or DefaultColorTable if it is nil. Projects larger than 256 blocks in any
+// direction are split into several models placed by the scene graph.
+func (p *Project) Vox(colors ColorTable) *Vox {
+	if colors == nil {
+		colors = DefaultColorTable
+	}
+	v := &Vox{Version: 200}
+	v.Palette[0] = color.RGBA{}
+
+	// palette index for every project palette entry, 0 for air
+	var used int
+	indexOf := make(map[color.RGBA]uint8)
+	palette := p.Palette()
+	colorIndex := make([]uint8, len(palette))
+	for i, s := range palette {
+		if s.Name == air || len(blockBoxes(s.Properties)) == 0 {
+			continue
+		}
+		c, ok := colors.ColorOf(s.Properties)
+		if !ok {
+			c = defaultMeshColor
+		}
+		if idx, ok := indexOf[c]; ok {
+			colorIndex[i] = idx
+			continue
+		}
+		if used < 255 {
+			used++
+			v.Palette[used] = c
+			indexOf[c] = uint8(used)
+			colorIndex[i] = uint8(used)
+			continue
+		}
+		// the palette is full, use the closest colour already in it
+		best, bestDist := 1, -1
+		for k := 1; k <= used; k++ {
+			if d := colorDistance(c, v.Palette[k]); bestDist < 0 || d < bestDist {
+				best, bestDist = k, d
+			}
+		}
+		colorIndex[i] = uint8(best)
+	}
+
+	// Minecraft (x, y, z) is MagicaVoxel (x, Z-1-z, y)
+	sx, sy, sz := p.XRange(), p.ZRange(), p.YRange()
+	for ox := 0; ox < sx; ox += maxVoxSize {
+		for oy := 0; oy < sy; oy += maxVoxSize {
+			for oz := 0; oz < sz; oz += maxVoxSize {
+				m := VoxModel{Size: [3]int32{
+					int32(minInt(maxVoxSize, sx-ox)),
+					int32(minInt(maxVoxSize, sy-oy)),
+					int32(minInt(maxVoxSize, sz-oz)),
+				}}
+				for x := 0; x < int(m.Size[0]); x++ {
+					for y := 0; y < int(m.Size[1]); y++ {
+						for z := 0; z < int(m.Size[2]); z++ {
+							s := p.blocks.get(ox+x, oz+z, sy-1-(oy+y))
+							if c := colorIndex[s]; c != 0 {
+								m.Voxels = append(m.Voxels, Voxel{uint8(x), uint8(y), uint8(z), c})
+							}
+						}
+					}
+				}
+				t := identityVoxTransform
+				t.Translation = [3]int32{int32(ox) + m.Size[0]/2, int32(oy) + m.Size[1]/2, int32(oz) + m.Size[2]/2}
+				v.Instances = append(v.Instances, VoxInstance{Model: len(v.Models), Transform: t})
+				v.Models = append(v.Models, m)
+			}
+		}
+	}
+	return v
+}
+
+// Encode writes the file with a scene graph of one group holding every instance.
+func (v *Vox) Encode(w io.Writer) error {
+	var body bytes.Buffer
+	for _, m := range v.Models {
+		writeVoxChunk(&body, "SIZE", func(b *voxWriter) {
+			b.int32(m.Size[0])
+			b.int32(m.Size[1])
+			b.int32(m.Size[2])
+		})
+		writeVoxChunk(&body, "XYZI", func(b *voxWriter) {
+			b.int32(int32(len(m.Voxels)))
+			for _, vx := range m.Voxels {
+				b.Write([]byte{vx.X, vx.Y, vx.Z, vx.Color})
+			}
+		})
+	}
+
+	// node 0 is the root transform, node 1 the group, then a transform and a
+	// shape node for every instance
+	writeVoxChunk(&body, "nTRN", func(b *voxWriter) {
+		b.int32(0)
+		b.dict(nil)
+		b.int32(1)
+		b.int32(-1)
+		b.int32(-1)
+		b.int32(1)
+		b.dict(nil)
+	})
+	writeVoxChunk(&body, "nGRP", func(b *voxWriter) {
+		b.int32(1)
+		b.dict(nil)
+		b.int32(int32(len(v.Instances)))
+		for i := range v.Instances {
+			b.int32(int32(2 + 2*i))
+		}
+	})
+	for i, in := range v.Instances {
+		writeVoxChunk(&body, "nTRN", func(b *voxWriter) {
+			b.int32(int32(2 + 2*i))
+			b.dict(nil)
+			b.int32(int32(3 + 2*i))
+			b.int32(-1)
+			b.int32(0)
+			b.int32(1)
+			b.dict(in.Transform.frame())
+		})
+		writeVoxChunk(&body, "nSHP", func(b *voxWriter) {
+			b.int32(int32(3 + 2*i))
+			b.dict(nil)
+			b.int32(1)
+			b.int32(int32(in.Model))
+			b.dict(nil)
+		})
+	}
+	writeVoxChunk(&body, "RGBA", func(b *voxWriter) {
+		for i := 1; i <= 256; i++ {
+			c := v.Palette[i%256]
+			b.Write([]byte{c.R, c.G, c.B, c.A})
+		}
+	})
+
+	var head bytes.Buffer
+	head.WriteString("VOX ")
+	binary.Write(&head, binary.LittleEndian, v.Version)
+	head.WriteString("MAIN")
+	binary.Write(&head, binary.LittleEndian, int32(0))
+	binary.Write(&head, binary.LittleEndian, int32(body.Len()))
+	if _, err := w.Write(head.Bytes()); err != nil {
+		return err
+	}
+	_, err := w.Write(body.Bytes())
+	return err
+}
+
+// frame encodes the transform as the attributes of an nTRN frame.
+func (t VoxTransform) frame() map[string]string {
+	d := map[string]string{
+		"_t": fmt.Sprintf("%d %d %d", t.Translation[0], t.Translation[1], t.Translation[2]),
+	}
+	if t.Rotation != identityVoxTransform.Rotation {
+		var r int
+		var cols [3]int
+		for row := 0; row < 3; row++ {
+			for col := 0; col < 3; col++ {
+				if t.Rotation[row][col] != 0 {
+					cols[row] = col
+					if t.Rotation[row][col] < 0 {
+						r |= 1 << (4 + row)
+					}
+				}
+			}
+		}
+		r |= cols[0] | cols[1]<<2
+		d["_r"] = strconv.Itoa(r)
+	}
+	return d
+}
+
+type voxWriter struct {
+	bytes.Buffer
+}
+
+func (w *voxWriter) int32(n int32) {
+	binary.Write(w, binary.LittleEndian, n)
+}
+
+func (w *voxWriter) dict(d map[string]string) {
+	keys := make([]string, 0, len(d))
+	for k := range d {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	w.int32(int32(len(d)))
+	for _, k := range keys {
+		w.int32(int32(len(k)))
+		w.WriteString(k)
+		w.int32(int32(len(d[k])))
+		w.WriteString(d[k])
+	}
+}
+
+func writeVoxChunk(w *bytes.Buffer, id string, content func(b *voxWriter)) {
+	var b voxWriter
+	content(&b)
+	w.WriteString(id)
+	binary.Write(w, binary.LittleEndian, int32(b.Len()))
+	binary.Write(w, binary.LittleEndian, int32(0))
+	w.Write(b.Bytes())
+}
@@ -0,0 +1,77 @@
+package schematic
+
+import (
+	"bytes"
+	"github.com/Tnze/go-mc/level/block"
+	"testing"
+)
+
+func TestVoxRoundTrip(t *testing.T) {
+	// wider than one MagicaVoxel model, so it has to be split
+	project := NewProject("vox", 300, 3, 2)
+	blockMap := map[Pos]block.Block{
+		{0, 0, 0}:   block.RedWool{},
+		{299, 2, 1}: block.Stone{},
+		{256, 0, 1}: block.OakPlanks{},
+		{255, 1, 0}: block.LapisBlock{},
+	}
+	for k, v := range blockMap {
+		project.SetBlock(k.x, k.y, k.z, v)
+	}
+
+	v := project.Vox(nil)
+	if len(v.Models) != 2 {
+		t.Fatalf("Error, models: %d, want 2", len(v.Models))
+	}
+	var buf bytes.Buffer
+	if err := v.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	p, err := LoadFromVox("vox.vox", &buf, VoxOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Size() != project.Size() {
+		t.Fatalf("Error, size: %v, want %v", p.Size(), project.Size())
+	}
+	for k, v := range blockMap {
+		if b := p.GetBlock(k.x, k.y, k.z).Properties; b != v {
+			t.Fatalf("Error, pos: %v, wrong block: %s, correct blocks: %s", k, b.ID(), v.ID())
+		}
+	}
+	if p.MetaData.TotalBlocks != int32(len(blockMap)) {
+		t.Fatalf("Error, total blocks: %d, want %d", p.MetaData.TotalBlocks, len(blockMap))
+	}
+}
+
+func TestVoxSceneRotation(t *testing.T) {
+	// _r = 0b0010001: rows pick columns 1, 0, 2 with the first row negated,
+	// a 90 degree turn around Z
+	tr := parseVoxFrame(map[string]string{"_r": "17", "_t": "1 2 3"})
+	want := [3][3]int32{{0, -1, 0}, {1, 0, 0}, {0, 0, 1}}
+	if tr.Rotation != want || tr.Translation != [3]int32{1, 2, 3} {
+		t.Fatalf("Error, transform: %v", tr)
+	}
+	if back := parseVoxFrame(tr.frame()); back != tr {
+		t.Fatalf("Error, frame round trip: %v, want %v", back, tr)
+	}
+	if p := tr.apply([3]int32{1, 0, 0}); p != [3]int32{1, 3, 3} {
+		t.Fatalf("Error, apply: %v", p)
+	}
+}
+
+func TestVoxNegativeCount(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("VOX ")
+	buf.Write([]byte{150, 0, 0, 0})
+	writeVoxChunk(&buf, "MAIN", func(b *voxWriter) {})
+	writeVoxChunk(&buf, "SIZE", func(b *voxWriter) {
+		b.int32(1)
+		b.int32(1)
+		b.int32(1)
+	})
+	writeVoxChunk(&buf, "XYZI", func(b *voxWriter) { b.int32(-1) })
+	if _, err := ReadVoxFile(&buf); err == nil {
+		t.Fatalf("Error, negative voxel count read without error")
+	}
+}
@@ -0,0 +1,290 @@
+package schematic
+
+import (
+	"context"
+	"fmt"
+	"github.com/Tnze/go-mc/level/block"
+	"math"
+	"sort"
+)
+
+type Axis int
+
+const (
+	AxisY Axis = iota
+	AxisX
+	AxisZ
+)
+
+type VoxelizeOptions struct {
+	//Resolution is the number of blocks along the longest side of the model.
+	//If it is 0, Scale is used instead.
+	Resolution int
+
+	//Scale is the number of blocks per model unit
+	Scale float64
+
+	//UpAxis is the model axis that becomes Y in the project
+	UpAxis Axis
+
+	//Rotation in degrees around X, Y and Z, applied in that order after UpAxis
+	Rotation Vec3
+
+	//Solid fills the interior of closed meshes, otherwise only the shell is generated
+	Solid bool
+
+	//Block is used for the interior and for triangles without colour, default stone
+	Block block.Block
+
+	//Colors maps triangle colours to blocks, triangle colours are ignored if it is nil
+	Colors ColorTable
+}
+
+// Voxelize converts a triangle mesh to a project.
+func Voxelize(name string, m *Mesh, opt VoxelizeOptions) (*Project, error) {
+	return voxelize(name, m, opt, nil)
+}
+
+// VoxelizeContext is Voxelize stopping when ctx is done. The progress counts
+// triangles, twice when the interior is filled.
+func VoxelizeContext(ctx context.Context, name string, m *Mesh, opt VoxelizeOptions, progress Progress) (*Project, error) {
+	total := int64(len(m.Triangles))
+	if opt.Solid {
+		total *= 2
+	}
+	return voxelize(name, m, opt, newTracker(ctx, progress, total, 256))
+}
+
+func voxelize(name string, m *Mesh, opt VoxelizeOptions, tr *tracker) (*Project, error) {
+	if len(m.Triangles) == 0 {
+		return nil, fmt.Errorf("voxelize: mesh has no triangles")
+	}
+	if opt.Resolution <= 0 && opt.Scale <= 0 {
+		return nil, fmt.Errorf("voxelize: either resolution or scale must be positive")
+	}
+	if opt.Block == nil {
+		opt.Block = block.Stone{}
+	}
+
+	tris := make([]Triangle, len(m.Triangles))
+	lower := Vec3{math.Inf(1), math.Inf(1), math.Inf(1)}
+	upper := Vec3{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+	for i, t := range m.Triangles {
+		for v := range t.V {
+			t.V[v] = opt.transform(t.V[v])
+			lower = Vec3{math.Min(lower.X, t.V[v].X), math.Min(lower.Y, t.V[v].Y), math.Min(lower.Z, t.V[v].Z)}
+			upper = Vec3{math.Max(upper.X, t.V[v].X), math.Max(upper.Y, t.V[v].Y), math.Max(upper.Z, t.V[v].Z)}
+		}
+		tris[i] = t
+	}
+
+	extent := upper.Sub(lower)
+	scale := opt.Scale
+	if opt.Resolution > 0 {
+		longest := math.Max(extent.X, math.Max(extent.Y, extent.Z))
+		if longest == 0 {
+			return nil, fmt.Errorf("voxelize: mesh has no extent")
+		}
+		scale = float64(opt.Resolution) / longest
+	}
+	size := Vec3D{gridSize(extent.X * scale), gridSize(extent.Y * scale), gridSize(extent.Z * scale)}
+	for i := range tris {
+		for v := range tris[i].V {
+			tris[i].V[v] = tris[i].V[v].Sub(lower).Scale(scale)
+		}
+	}
+
+	p := NewProject(name, int(size.X), int(size.Y), int(size.Z))
+	for _, t := range tris {
+		if err := tr.add(1); err != nil {
+			return nil, err
+		}
+		b := opt.Block
+		if t.HasColor && opt.Colors != nil {
+			b = opt.Colors.Nearest(t.Color)
+		}
+		voxelizeTriangle(p, size, t, b)
+	}
+	if opt.Solid {
+		if err := fillInterior(p, size, tris, opt.Block, tr); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func gridSize(f float64) int32 {
+	// small epsilon so that an exact fit does not add a layer
+	return int32(math.Max(1, math.Ceil(f-1e-9)))
+}
+
+func (opt *VoxelizeOptions) transform(v Vec3) Vec3 {
+	switch opt.UpAxis {
+	case AxisZ:
+		v = Vec3{v.X, v.Z, -v.Y}
+	case AxisX:
+		v = Vec3{-v.Y, v.X, v.Z}
+	}
+	if a := opt.Rotation.X * math.Pi / 180; a != 0 {
+		s, c := math.Sincos(a)
+		v = Vec3{v.X, v.Y*c - v.Z*s, v.Y*s + v.Z*c}
+	}
+	if a := opt.Rotation.Y * math.Pi / 180; a != 0 {
+		s, c := math.Sincos(a)
+		v = Vec3{v.X*c + v.Z*s, v.Y, -v.X*s + v.Z*c}
+	}
+	if a := opt.Rotation.Z * math.Pi / 180; a != 0 {
+		s, c := math.Sincos(a)
+		v = Vec3{v.X*c - v.Y*s, v.X*s + v.Y*c, v.Z}
+	}
+	return v
+}
+
+// voxelizeTriangle sets every voxel the triangle passes through.
+func voxelizeTriangle(p *Project, size Vec3D, t Triangle, b block.Block) {
+	lo := [3]int{}
+	hi := [3]int{}
+	lim := [3]int32{size.X, size.Y, size.Z}
+	for a := 0; a < 3; a++ {
+		mn, mx := math.Inf(1), math.Inf(-1)
+		for _, v := range t.V {
+			c := component(v, a)
+			mn, mx = math.Min(mn, c), math.Max(mx, c)
+		}
+		lo[a] = clampInt(int(math.Floor(mn)), 0, int(lim[a])-1)
+		hi[a] = clampInt(int(math.Floor(mx)), 0, int(lim[a])-1)
+	}
+	for x := lo[0]; x <= hi[0]; x++ {
+		for y := lo[1]; y <= hi[1]; y++ {
+			for z := lo[2]; z <= hi[2]; z++ {
+				if p.GetBlock(x, y, z).Name != air {
+					continue
+				}
+				center := Vec3{float64(x) + 0.5, float64(y) + 0.5, float64(z) + 0.5}
+				if triBoxOverlap(center, 0.5, t.V) {
+					p.SetBlock(x, y, z, b)
+				}
+			}
+		}
+	}
+}
+
+// fillInterior casts a ray along Y through the centre of every column and fills
+// the voxels between pairs of surface crossings (scanline parity).
+func fillInterior(p *Project, size Vec3D, tris []Triangle, b block.Block, t *tracker) error {
+	// the ray is moved off the voxel centre slightly so it never runs exactly
+	// through a shared edge, which would count one crossing twice
+	const dx, dz = 1.3e-7, 2.9e-7
+	columns := make([][]float64, size.X*size.Z)
+	for _, tri := range tris {
+		if err := t.add(1); err != nil {
+			return err
+		}
+		x0 := clampInt(int(math.Floor(math.Min(tri.V[0].X, math.Min(tri.V[1].X, tri.V[2].X)))), 0, int(size.X)-1)
+		x1 := clampInt(int(math.Floor(math.Max(tri.V[0].X, math.Max(tri.V[1].X, tri.V[2].X)))), 0, int(size.X)-1)
+		z0 := clampInt(int(math.Floor(math.Min(tri.V[0].Z, math.Min(tri.V[1].Z, tri.V[2].Z)))), 0, int(size.Z)-1)
+		z1 := clampInt(int(math.Floor(math.Max(tri.V[0].Z, math.Max(tri.V[1].Z, tri.V[2].Z)))), 0, int(size.Z)-1)
+		for x := x0; x <= x1; x++ {
+			for z := z0; z <= z1; z++ {
+				if y, ok := rayHitY(tri.V, float64(x)+0.5+dx, float64(z)+0.5+dz); ok {
+					i := z*int(size.X) + x
+					columns[i] = append(columns[i], y)
+				}
+			}
+		}
+	}
+	for i, hits := range columns {
+		if i%progressStep == 0 {
+			if err := t.err(); err != nil {
+				return err
+			}
+		}
+		x, z := i%int(size.X), i/int(size.X)
+		sort.Float64s(hits)
+		for h := 0; h+1 < len(hits); h += 2 {
+			y0 := clampInt(int(math.Ceil(hits[h]-0.5)), 0, int(size.Y))
+			y1 := clampInt(int(math.Floor(hits[h+1]-0.5)), -1, int(size.Y)-1)
+			for y := y0; y <= y1; y++ {
+				if p.GetBlock(x, y, z).Name == air {
+					p.SetBlock(x, y, z, b)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// rayHitY intersects the vertical line through (x, z) with the triangle.
+func rayHitY(v [3]Vec3, x, z float64) (float64, bool) {
+	d := (v[1].Z-v[2].Z)*(v[0].X-v[2].X) + (v[2].X-v[1].X)*(v[0].Z-v[2].Z)
+	if d == 0 {
+		return 0, false // triangle is parallel to the ray
+	}
+	a := ((v[1].Z-v[2].Z)*(x-v[2].X) + (v[2].X-v[1].X)*(z-v[2].Z)) / d
+	b := ((v[2].Z-v[0].Z)*(x-v[2].X) + (v[0].X-v[2].X)*(z-v[2].Z)) / d
+	c := 1 - a - b
+	if a < 0 || b < 0 || c < 0 {
+		return 0, false
+	}
+	return a*v[0].Y + b*v[1].Y + c*v[2].Y, true
+}
+
+// triBoxOverlap is the separating axis test by Tomas Akenine-Möller.
+func triBoxOverlap(center Vec3, half float64, tri [3]Vec3) bool {
+	v0, v1, v2 := tri[0].Sub(center), tri[1].Sub(center), tri[2].Sub(center)
+	e := [3]Vec3{v1.Sub(v0), v2.Sub(v1), v0.Sub(v2)}
+
+	// 9 axes from the cross products of the box and triangle edges
+	for _, edge := range e {
+		for a := 0; a < 3; a++ {
+			var axis Vec3
+			switch a {
+			case 0:
+				axis = Vec3{0, -edge.Z, edge.Y}
+			case 1:
+				axis = Vec3{edge.Z, 0, -edge.X}
+			case 2:
+				axis = Vec3{-edge.Y, edge.X, 0}
+			}
+			p0, p1, p2 := v0.Dot(axis), v1.Dot(axis), v2.Dot(axis)
+			r := half * (math.Abs(axis.X) + math.Abs(axis.Y) + math.Abs(axis.Z))
+			if math.Min(p0, math.Min(p1, p2)) > r || math.Max(p0, math.Max(p1, p2)) < -r {
+				return false
+			}
+		}
+	}
+
+	// the box face normals
+	for a := 0; a < 3; a++ {
+		c0, c1, c2 := component(v0, a), component(v1, a), component(v2, a)
+		if math.Min(c0, math.Min(c1, c2)) > half || math.Max(c0, math.Max(c1, c2)) < -half {
+			return false
+		}
+	}
+
+	// the triangle normal
+	n := e[0].Cross(e[1])
+	r := half * (math.Abs(n.X) + math.Abs(n.Y) + math.Abs(n.Z))
+	return math.Abs(n.Dot(v0)) <= r
+}
+
+func component(v Vec3, axis int) float64 {
+	switch axis {
+	case 0:
+		return v.X
+	case 1:
+		return v.Y
+	default:
+		return v.Z
+	}
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,87 @@
+package schematic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/Tnze/go-mc/level/block"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestVoxelizeOBJ(t *testing.T) {
+	m, err := LoadOBJ("testdata/cube.obj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Triangles) != 12 {
+		t.Fatalf("Error, triangles: %d, want 12", len(m.Triangles))
+	}
+
+	hollow, err := Voxelize("cube", m, VoxelizeOptions{Resolution: 8, Block: block.Stone{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := hollow.Size(); s != (Vec3D{8, 8, 8}) {
+		t.Fatalf("Error, size: %v", s)
+	}
+	if hollow.MetaData.TotalBlocks != 8*8*8-6*6*6 {
+		t.Fatalf("Error, hollow blocks: %d, want %d", hollow.MetaData.TotalBlocks, 8*8*8-6*6*6)
+	}
+
+	solid, err := Voxelize("cube", m, VoxelizeOptions{Resolution: 8, Solid: true, Colors: DefaultColorTable})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if solid.MetaData.TotalBlocks != 8*8*8 {
+		t.Fatalf("Error, solid blocks: %d, want %d", solid.MetaData.TotalBlocks, 8*8*8)
+	}
+	red := DefaultColorTable.Nearest(rgb(161, 38, 33))
+	if b := solid.GetBlock(4, 0, 4).Properties; b != red {
+		t.Fatalf("Error, bottom face: %s, want %s", b.ID(), red.ID())
+	}
+	if b := solid.GetBlock(4, 4, 4).Properties; b != (block.Stone{}) {
+		t.Fatalf("Error, interior: %s, want stone", b.ID())
+	}
+}
+
+func TestVoxelizeSTL(t *testing.T) {
+	f, err := os.Open("testdata/tetrahedron.stl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	ascii, err := ReadSTL(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the same tetrahedron as a binary file, with a header starting with "solid"
+	buf := make([]byte, 84)
+	copy(buf, "solid binary")
+	binary.LittleEndian.PutUint32(buf[80:], uint32(len(ascii.Triangles)))
+	for _, tri := range ascii.Triangles {
+		buf = append(buf, make([]byte, 12)...)
+		for _, v := range tri.V {
+			for _, c := range []float64{v.X, v.Y, v.Z} {
+				buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(float32(c)))
+			}
+		}
+		buf = append(buf, 0, 0)
+	}
+	bin, err := ReadSTL(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bin.Triangles) != 4 || bin.Triangles[3] != ascii.Triangles[3] {
+		t.Fatalf("Error, binary and ascii stl differ: %v, %v", bin.Triangles, ascii.Triangles)
+	}
+
+	p, err := Voxelize("tetrahedron", bin, VoxelizeOptions{Resolution: 10, Solid: true, UpAxis: AxisZ})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.GetBlock(1, 1, 8).Name == air || p.GetBlock(9, 9, 0).Name != air {
+		t.Fatalf("Error, tetrahedron corner blocks are wrong")
+	}
+}
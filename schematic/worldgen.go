@@ -0,0 +1,259 @@
+package schematic
+
+import (
+	"archive/zip"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"strings"
+)
+
+// DatapackOptions configure the data pack that adds a project as a worldgen
+// structure. Zero values are replaced by the defaults given for each field.
+type DatapackOptions struct {
+	//Namespace of the pack's resources, required
+	Namespace string
+
+	//Name of the structure, template pool and structure set, the project's
+	//region name in lower case by default
+	Name string
+
+	//Description of pack.mcmeta, the name by default
+	Description string
+
+	//Biomes where the structure generates, ids or a single tag starting with #,
+	//default #minecraft:is_overworld
+	Biomes []string
+
+	//Step of the generation, default surface_structures
+	Step string
+
+	//TerrainAdaptation is none, beard_thin, beard_box, bury or encapsulate,
+	//default beard_thin
+	TerrainAdaptation string
+
+	//Heightmap the structure is placed on, default WORLD_SURFACE_WG
+	Heightmap string
+
+	//StartHeight Offset from the heightmap
+	StartHeight int
+
+	//Spacing Average distance between two structures in chunks, default 32
+	Spacing int
+
+	//Separation Minimum distance between two structures in chunks, default 8
+	Separation int
+
+	//Salt of the random placement, derived from the name by default
+	Salt int
+}
+
+// maxDistanceFromCenter is the largest max_distance_from_center of a jigsaw
+// structure.
+const maxDistanceFromCenter = 128
+
+var (
+	generationSteps = []string{"raw_generation", "lakes", "local_modifications", "underground_structures",
+		"surface_structures", "strongholds", "underground_ores", "underground_decoration", "fluid_springs",
+		"vegetal_decoration", "top_layer_modification"}
+	terrainAdaptations = []string{"none", "beard_thin", "beard_box", "bury", "encapsulate"}
+	heightmaps         = []string{"WORLD_SURFACE_WG", "WORLD_SURFACE", "OCEAN_FLOOR_WG", "OCEAN_FLOOR",
+		"MOTION_BLOCKING", "MOTION_BLOCKING_NO_LEAVES"}
+)
+
+// check sets the defaults and validates the options.
+func (o *DatapackOptions) check(p *Project) error {
+	if o.Name == "" {
+		o.Name = strings.ToLower(p.RegionName)
+	}
+	if o.Description == "" {
+		o.Description = o.Name
+	}
+	if len(o.Biomes) == 0 {
+		o.Biomes = []string{"#minecraft:is_overworld"}
+	}
+	if o.Step == "" {
+		o.Step = "surface_structures"
+	}
+	if o.TerrainAdaptation == "" {
+		o.TerrainAdaptation = "beard_thin"
+	}
+	if o.Heightmap == "" {
+		o.Heightmap = "WORLD_SURFACE_WG"
+	}
+	if o.Spacing == 0 {
+		o.Spacing = 32
+	}
+	if o.Separation == 0 {
+		o.Separation = 8
+	}
+	if o.Salt == 0 {
+		h := fnv.New32a()
+		h.Write([]byte(o.Namespace + ":" + o.Name))
+		o.Salt = int(h.Sum32() & 0x7fffffff)
+	}
+
+	if err := validResource(o.Namespace, o.Name); err != nil {
+		return err
+	}
+	for _, b := range o.Biomes {
+		if err := validLocation(strings.TrimPrefix(b, "#")); err != nil {
+			return fmt.Errorf("biome %q: %w", b, err)
+		}
+		// the game reads either one tag or a list of biomes
+		if strings.HasPrefix(b, "#") && len(o.Biomes) > 1 {
+			return fmt.Errorf("biome tag %q must be the only biome", b)
+		}
+	}
+	if !oneOf(o.Step, generationSteps) {
+		return fmt.Errorf("unknown generation step %q", o.Step)
+	}
+	if !oneOf(o.TerrainAdaptation, terrainAdaptations) {
+		return fmt.Errorf("unknown terrain adaptation %q", o.TerrainAdaptation)
+	}
+	if !oneOf(o.Heightmap, heightmaps) {
+		return fmt.Errorf("unknown heightmap %q", o.Heightmap)
+	}
+	if o.Spacing < 1 || o.Spacing > 4096 {
+		return fmt.Errorf("spacing %d out of range 1 to 4096", o.Spacing)
+	}
+	if o.Separation < 0 || o.Separation >= o.Spacing {
+		return fmt.Errorf("separation %d must be at least 0 and less than the spacing %d", o.Separation, o.Spacing)
+	}
+	if size := p.Size(); max32(size.X, size.Z) > maxDistanceFromCenter {
+		return fmt.Errorf("worldgen structures can't be wider than %d blocks, the project is %d by %d", maxDistanceFromCenter, size.X, size.Z)
+	}
+	if PackFormat(p.MinecraftDataVersion) < 10 {
+		return fmt.Errorf("worldgen structures need Minecraft 1.19 or later, the data version is %d", p.MinecraftDataVersion)
+	}
+	return nil
+}
+
+func oneOf(s string, list []string) bool {
+	for _, l := range list {
+		if s == l {
+			return true
+		}
+	}
+	return false
+}
+
+// validLocation checks a resource location with an optional namespace.
+func validLocation(s string) error {
+	namespace, path, ok := strings.Cut(s, ":")
+	if !ok {
+		namespace, path = "minecraft", s
+	}
+	return validResource(namespace, path)
+}
+
+// WriteDatapack writes a data pack to dir that generates the project as a
+// worldgen structure: the structure file, a template pool with it as the only
+// element, the jigsaw structure and a structure set spreading it randomly.
+// pack.mcmeta gets the pack format of the project's data version. Projects
+// wider than 128 blocks are an error, as the game can't place them.
+func (p *Project) WriteDatapack(dir string, opt DatapackOptions) error {
+	if err := opt.check(p); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return p.writeDatapack(dirPack(dir), opt)
+}
+
+// WriteDatapackZip is WriteDatapack writing a zip file to w.
+func (p *Project) WriteDatapackZip(w io.Writer, opt DatapackOptions) error {
+	if err := opt.check(p); err != nil {
+		return err
+	}
+	z := zip.NewWriter(w)
+	if err := p.writeDatapack(zipPack{z}, opt); err != nil {
+		return err
+	}
+	return z.Close()
+}
+
+func (p *Project) writeDatapack(w packWriter, opt DatapackOptions) error {
+	format := PackFormat(p.MinecraftDataVersion)
+	structures, _ := packDirs(format)
+	id := opt.Namespace + ":" + opt.Name
+	data := "data/" + opt.Namespace + "/"
+
+	if err := writePackMeta(w, format, opt.Description); err != nil {
+		return err
+	}
+	if err := writePackFile(w, data+structures+"/"+opt.Name+".nbt", p.Nbt().Encode); err != nil {
+		return err
+	}
+
+	type element struct {
+		ElementType string `json:"element_type"`
+		Location    string `json:"location"`
+		Projection  string `json:"projection"`
+		Processors  string `json:"processors"`
+	}
+	type weighted struct {
+		Weight  int     `json:"weight"`
+		Element element `json:"element"`
+	}
+	pool := struct {
+		Name     string     `json:"name"`
+		Fallback string     `json:"fallback"`
+		Elements []weighted `json:"elements"`
+	}{id, "minecraft:empty", []weighted{{1, element{"minecraft:single_pool_element", id, "rigid", "minecraft:empty"}}}}
+	if err := writePackJSON(w, data+"worldgen/template_pool/"+opt.Name+".json", pool); err != nil {
+		return err
+	}
+
+	// a tag is written as a string, ids as a list
+	var biomes any = opt.Biomes
+	if len(opt.Biomes) == 1 && strings.HasPrefix(opt.Biomes[0], "#") {
+		biomes = opt.Biomes[0]
+	}
+	size := p.Size()
+	structure := struct {
+		Type                    string         `json:"type"`
+		Biomes                  any            `json:"biomes"`
+		Step                    string         `json:"step"`
+		SpawnOverrides          struct{}       `json:"spawn_overrides"`
+		TerrainAdaptation       string         `json:"terrain_adaptation"`
+		StartPool               string         `json:"start_pool"`
+		Size                    int            `json:"size"`
+		StartHeight             map[string]int `json:"start_height"`
+		ProjectStartToHeightmap string         `json:"project_start_to_heightmap"`
+		MaxDistanceFromCenter   int            `json:"max_distance_from_center"`
+		UseExpansionHack        bool           `json:"use_expansion_hack"`
+	}{
+		Type:                    "minecraft:jigsaw",
+		Biomes:                  biomes,
+		Step:                    opt.Step,
+		TerrainAdaptation:       opt.TerrainAdaptation,
+		StartPool:               id,
+		Size:                    1,
+		StartHeight:             map[string]int{"absolute": opt.StartHeight},
+		ProjectStartToHeightmap: opt.Heightmap,
+		// the whole structure is within this distance of its start
+		MaxDistanceFromCenter: max(int(max32(size.X, size.Z)), 1),
+	}
+	if err := writePackJSON(w, data+"worldgen/structure/"+opt.Name+".json", structure); err != nil {
+		return err
+	}
+
+	type entry struct {
+		Structure string `json:"structure"`
+		Weight    int    `json:"weight"`
+	}
+	type placement struct {
+		Type       string `json:"type"`
+		Spacing    int    `json:"spacing"`
+		Separation int    `json:"separation"`
+		Salt       int    `json:"salt"`
+	}
+	set := struct {
+		Structures []entry   `json:"structures"`
+		Placement  placement `json:"placement"`
+	}{[]entry{{id, 1}}, placement{"minecraft:random_spread", opt.Spacing, opt.Separation, opt.Salt}}
+	return writePackJSON(w, data+"worldgen/structure_set/"+opt.Name+".json", set)
+}
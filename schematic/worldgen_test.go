@@ -0,0 +1,113 @@
+package schematic
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"github.com/Tnze/go-mc/level/block"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteDatapackZip(t *testing.T) {
+	p := NewProject("Tower", 5, 12, 5, WithDataVersion(3953))
+	p.Fill(p.Bounds(), Single(block.StoneBricks{}), nil)
+	var file bytes.Buffer
+	if err := p.WriteDatapackZip(&file, DatapackOptions{Namespace: "build", Spacing: 20, Separation: 5}); err != nil {
+		t.Fatalf("Error, %v", err)
+	}
+	z, err := zip.NewReader(bytes.NewReader(file.Bytes()), int64(file.Len()))
+	if err != nil {
+		t.Fatalf("Error, %v", err)
+	}
+	files := make(map[string]*zip.File)
+	for _, f := range z.File {
+		files[f.Name] = f
+	}
+	for _, name := range []string{"pack.mcmeta", "data/build/structure/tower.nbt", "data/build/worldgen/template_pool/tower.json",
+		"data/build/worldgen/structure/tower.json", "data/build/worldgen/structure_set/tower.json"} {
+		if files[name] == nil {
+			t.Fatalf("Error, %s missing from %v", name, files)
+		}
+	}
+	readJSON := func(name string, v any) {
+		r, err := files[name].Open()
+		if err != nil {
+			t.Fatalf("Error, %v", err)
+		}
+		defer r.Close()
+		if err := json.NewDecoder(r).Decode(v); err != nil {
+			t.Fatalf("Error, %s: %v", name, err)
+		}
+	}
+
+	var meta struct {
+		Pack struct {
+			PackFormat int `json:"pack_format"`
+		} `json:"pack"`
+	}
+	readJSON("pack.mcmeta", &meta)
+	if meta.Pack.PackFormat != 48 {
+		t.Fatalf("Error, pack format %d, want 48", meta.Pack.PackFormat)
+	}
+	var set struct {
+		Structures []struct{ Structure string }
+		Placement  struct{ Spacing, Separation int }
+	}
+	readJSON("data/build/worldgen/structure_set/tower.json", &set)
+	if len(set.Structures) != 1 || set.Structures[0].Structure != "build:tower" || set.Placement.Spacing != 20 || set.Placement.Separation != 5 {
+		t.Fatalf("Error, structure set %+v", set)
+	}
+	var structure struct {
+		Biomes            string
+		StartPool         string `json:"start_pool"`
+		TerrainAdaptation string `json:"terrain_adaptation"`
+	}
+	readJSON("data/build/worldgen/structure/tower.json", &structure)
+	if structure.Biomes != "#minecraft:is_overworld" || structure.StartPool != "build:tower" || structure.TerrainAdaptation != "beard_thin" {
+		t.Fatalf("Error, structure %+v", structure)
+	}
+
+	r, _ := files["data/build/structure/tower.nbt"].Open()
+	defer r.Close()
+	q, err := LoadFromNbt("tower.nbt", r)
+	if err != nil || q.MetaData.TotalBlocks != 5*12*5 {
+		t.Fatalf("Error, structure file: %v", err)
+	}
+}
+
+func TestWriteDatapack(t *testing.T) {
+	p := NewProject("hut", 3, 3, 3, WithDataVersion(3465))
+	p.SetBlock(1, 1, 1, block.OakPlanks{})
+	dir := t.TempDir()
+	if err := p.WriteDatapack(dir, DatapackOptions{Namespace: "build", Biomes: []string{"plains", "minecraft:forest"}}); err != nil {
+		t.Fatalf("Error, %v", err)
+	}
+	// 1.20.1 still uses the plural directory
+	if _, err := os.Stat(filepath.Join(dir, "data/build/structures/hut.nbt")); err != nil {
+		t.Fatalf("Error, %v", err)
+	}
+
+	for _, opt := range []DatapackOptions{
+		{},
+		{Namespace: "build", Name: "Hut"},
+		{Namespace: "build", Step: "surface"},
+		{Namespace: "build", TerrainAdaptation: "flat"},
+		{Namespace: "build", Spacing: 8, Separation: 8},
+		{Namespace: "build", Biomes: []string{"#Plains"}},
+		{Namespace: "build", Biomes: []string{"#minecraft:is_forest", "plains"}},
+	} {
+		if err := p.WriteDatapack(dir, opt); err == nil {
+			t.Fatalf("Error, options %+v accepted", opt)
+		}
+	}
+	old := NewProject("hut", 3, 3, 3, WithDataVersion(2586))
+	if err := old.WriteDatapack(dir, DatapackOptions{Namespace: "build"}); err == nil {
+		t.Fatalf("Error, datapack for 1.16 accepted")
+	}
+	wide := NewProject("wall", maxDistanceFromCenter+1, 1, 1, WithDataVersion(3465))
+	if err := wide.WriteDatapack(dir, DatapackOptions{Namespace: "build"}); err == nil {
+		t.Fatalf("Error, structure wider than %d accepted", maxDistanceFromCenter)
+	}
+}
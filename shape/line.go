@@ -0,0 +1,140 @@
+package shape
+
+import (
+	"github.com/elvis972602/go-litematica-tools/schematic"
+	"math"
+)
+
+// set is a shape made of a precomputed list of blocks.
+type set struct {
+	blocks map[[3]int]bool
+	lo, hi [3]int
+}
+
+func newSet() *set {
+	return &set{
+		blocks: make(map[[3]int]bool),
+		lo:     [3]int{math.MaxInt, math.MaxInt, math.MaxInt},
+		hi:     [3]int{math.MinInt, math.MinInt, math.MinInt},
+	}
+}
+
+func (s *set) add(p [3]int) {
+	s.blocks[p] = true
+	for i := 0; i < 3; i++ {
+		s.lo[i], s.hi[i] = minInt(s.lo[i], p[i]), maxInt(s.hi[i], p[i])
+	}
+}
+
+// stamp adds the blocks within r of the center of p moved by off on every
+// axis, a ball centered on a corner of p when off is 0.5.
+func (s *set) stamp(p [3]int, off, r float64) {
+	lo, hi := int(math.Floor(off-r-eps)), int(math.Ceil(off+r+eps))
+	for dx := lo; dx <= hi; dx++ {
+		for dy := lo; dy <= hi; dy++ {
+			for dz := lo; dz <= hi; dz++ {
+				x, y, z := float64(dx)-off, float64(dy)-off, float64(dz)-off
+				if x*x+y*y+z*z <= r*r+eps {
+					s.add([3]int{p[0] + dx, p[1] + dy, p[2] + dz})
+				}
+			}
+		}
+	}
+}
+
+func (s *set) Bounds() (min, max [3]int) { return s.lo, s.hi }
+
+func (s *set) Contains(x, y, z int) bool { return s.blocks[[3]int{x, y, z}] }
+
+// Line is a 3D Bresenham line from a to b. Thicker lines place a ball of
+// diameter thickness at every block of the line, a thickness below 1 is 1.
+// Balls of an even diameter are centered on the corner of the block.
+func Line(a, b [3]int, thickness int) Shape {
+	thickness = maxInt(thickness, 1)
+	s := newSet()
+	off, r := float64(1-thickness%2)/2, float64(thickness)/2
+	for _, p := range bresenham(a, b) {
+		s.stamp(p, off, r)
+	}
+	return s
+}
+
+// QuadraticBezier is a tube of radius r along the curve with control points p0, p1 and p2.
+func QuadraticBezier(p0, p1, p2 schematic.Vec3, r float64) Shape {
+	return curve(func(t float64) schematic.Vec3 {
+		u := 1 - t
+		return p0.Scale(u * u).Add(p1.Scale(2 * u * t)).Add(p2.Scale(t * t))
+	}, []schematic.Vec3{p0, p1, p2}, r)
+}
+
+// CubicBezier is a tube of radius r along the curve with control points p0 to p3.
+func CubicBezier(p0, p1, p2, p3 schematic.Vec3, r float64) Shape {
+	return curve(func(t float64) schematic.Vec3 {
+		u := 1 - t
+		return p0.Scale(u * u * u).Add(p1.Scale(3 * u * u * t)).Add(p2.Scale(3 * u * t * t)).Add(p3.Scale(t * t * t))
+	}, []schematic.Vec3{p0, p1, p2, p3}, r)
+}
+
+// curve samples f densely enough that consecutive points are less than a
+// block apart, the control polygon length being an upper bound of the curve
+// length, and joins them with Bresenham lines.
+func curve(f func(t float64) schematic.Vec3, controls []schematic.Vec3, r float64) Shape {
+	length := 0.0
+	for i := 1; i < len(controls); i++ {
+		v := controls[i].Sub(controls[i-1])
+		length += math.Sqrt(v.Dot(v))
+	}
+	n := int(math.Ceil(length)) + 1
+	s := newSet()
+	prev := round(f(0))
+	for i := 1; i <= n; i++ {
+		p := round(f(float64(i) / float64(n)))
+		for _, q := range bresenham(prev, p) {
+			s.stamp(q, 0, r)
+		}
+		prev = p
+	}
+	return s
+}
+
+func round(v schematic.Vec3) [3]int {
+	return [3]int{int(math.Round(v.X)), int(math.Round(v.Y)), int(math.Round(v.Z))}
+}
+
+// bresenham returns the blocks of the line from a to b, both included.
+func bresenham(a, b [3]int) [][3]int {
+	var d, s [3]int
+	for i := 0; i < 3; i++ {
+		d[i] = b[i] - a[i]
+		s[i] = 1
+		if d[i] < 0 {
+			d[i], s[i] = -d[i], -1
+		}
+	}
+	// drive along the axis with the largest difference
+	m := 0
+	for i := 1; i < 3; i++ {
+		if d[i] > d[m] {
+			m = i
+		}
+	}
+	o1, o2 := (m+1)%3, (m+2)%3
+	e1, e2 := 2*d[o1]-d[m], 2*d[o2]-d[m]
+	p := a
+	points := make([][3]int, 0, d[m]+1)
+	for i := 0; i <= d[m]; i++ {
+		points = append(points, p)
+		if e1 > 0 {
+			p[o1] += s[o1]
+			e1 -= 2 * d[m]
+		}
+		if e2 > 0 {
+			p[o2] += s[o2]
+			e2 -= 2 * d[m]
+		}
+		e1 += 2 * d[o1]
+		e2 += 2 * d[o2]
+		p[m] += s[m]
+	}
+	return points
+}
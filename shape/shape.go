@@ -0,0 +1,250 @@
+// Package shape draws geometric primitives into a schematic.Project.
+package shape
+
+import (
+	"github.com/elvis972602/go-litematica-tools/schematic"
+	"math"
+)
+
+// eps keeps points lying exactly on a surface inside the shape despite
+// rounding errors.
+const eps = 1e-9
+
+type Shape interface {
+	//Bounds returns the smallest box, inclusive, holding every block of the shape
+	Bounds() (min, max [3]int)
+
+	Contains(x, y, z int) bool
+}
+
+// Draw places pat at every block of s inside the project and returns the
+// number of blocks set.
+func Draw(p *schematic.Project, s Shape, pat schematic.Pattern) int {
+	return draw(p, s, pat, false)
+}
+
+// DrawHollow only places the blocks of s that have a side facing outside of s.
+func DrawHollow(p *schematic.Project, s Shape, pat schematic.Pattern) int {
+	return draw(p, s, pat, true)
+}
+
+func draw(p *schematic.Project, s Shape, pat schematic.Pattern, hollow bool) int {
+	lo, hi := s.Bounds()
+	lo = [3]int{maxInt(lo[0], 0), maxInt(lo[1], 0), maxInt(lo[2], 0)}
+	hi = [3]int{minInt(hi[0], p.XRange()-1), minInt(hi[1], p.YRange()-1), minInt(hi[2], p.ZRange()-1)}
+	n := 0
+	for y := lo[1]; y <= hi[1]; y++ {
+		for z := lo[2]; z <= hi[2]; z++ {
+			for x := lo[0]; x <= hi[0]; x++ {
+				if !s.Contains(x, y, z) {
+					continue
+				}
+				if hollow && !onSurface(s, x, y, z) {
+					continue
+				}
+				p.SetBlock(x, y, z, pat.At(x, y, z).Properties)
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func onSurface(s Shape, x, y, z int) bool {
+	return !s.Contains(x+1, y, z) || !s.Contains(x-1, y, z) ||
+		!s.Contains(x, y+1, z) || !s.Contains(x, y-1, z) ||
+		!s.Contains(x, y, z+1) || !s.Contains(x, y, z-1)
+}
+
+type ellipsoid struct {
+	c, r schematic.Vec3
+}
+
+// Sphere is a ball of radius r around c.
+func Sphere(c schematic.Vec3, r float64) Shape {
+	return ellipsoid{c, schematic.Vec3{X: r, Y: r, Z: r}}
+}
+
+// Ellipsoid has the radii r along the X, Y and Z axes.
+func Ellipsoid(c, r schematic.Vec3) Shape {
+	return ellipsoid{c, r}
+}
+
+func (e ellipsoid) Bounds() (min, max [3]int) {
+	return bounds(e.c.Sub(e.r), e.c.Add(e.r))
+}
+
+func (e ellipsoid) Contains(x, y, z int) bool {
+	dx, dy, dz := (float64(x)-e.c.X)/e.r.X, (float64(y)-e.c.Y)/e.r.Y, (float64(z)-e.c.Z)/e.r.Z
+	return dx*dx+dy*dy+dz*dz <= 1+eps
+}
+
+type cone struct {
+	a, b   schematic.Vec3
+	r0, r1 float64
+}
+
+// Cylinder has its bottom centre at a and its top centre at b, so it can point in any direction.
+func Cylinder(a, b schematic.Vec3, r float64) Shape {
+	return cone{a, b, r, r}
+}
+
+// Cone has a base of radius r centred at base and its tip at apex.
+func Cone(base, apex schematic.Vec3, r float64) Shape {
+	return cone{base, apex, r, 0}
+}
+
+func (c cone) Bounds() (min, max [3]int) {
+	r := math.Max(c.r0, c.r1)
+	lo := schematic.Vec3{X: math.Min(c.a.X, c.b.X) - r, Y: math.Min(c.a.Y, c.b.Y) - r, Z: math.Min(c.a.Z, c.b.Z) - r}
+	hi := schematic.Vec3{X: math.Max(c.a.X, c.b.X) + r, Y: math.Max(c.a.Y, c.b.Y) + r, Z: math.Max(c.a.Z, c.b.Z) + r}
+	return bounds(lo, hi)
+}
+
+func (c cone) Contains(x, y, z int) bool {
+	axis := c.b.Sub(c.a)
+	l2 := axis.Dot(axis)
+	if l2 == 0 {
+		return false
+	}
+	q := vec(x, y, z).Sub(c.a)
+	t := q.Dot(axis) / l2
+	if t < -eps || t > 1+eps {
+		return false
+	}
+	r := c.r0 + (c.r1-c.r0)*t
+	d := q.Sub(axis.Scale(t))
+	return d.Dot(d) <= r*r+eps
+}
+
+type cuboid struct {
+	lo, hi [3]int
+	walls  bool
+}
+
+// Cuboid is the box between the corners a and b, inclusive.
+func Cuboid(a, b [3]int) Shape {
+	lo, hi := sortCorners(a, b)
+	return cuboid{lo, hi, false}
+}
+
+// Walls is the four vertical sides of the box between a and b, without floor and ceiling.
+func Walls(a, b [3]int) Shape {
+	lo, hi := sortCorners(a, b)
+	return cuboid{lo, hi, true}
+}
+
+func (c cuboid) Bounds() (min, max [3]int) { return c.lo, c.hi }
+
+func (c cuboid) Contains(x, y, z int) bool {
+	if x < c.lo[0] || y < c.lo[1] || z < c.lo[2] || x > c.hi[0] || y > c.hi[1] || z > c.hi[2] {
+		return false
+	}
+	if c.walls {
+		return x == c.lo[0] || x == c.hi[0] || z == c.lo[2] || z == c.hi[2]
+	}
+	return true
+}
+
+type torus struct {
+	c, n         schematic.Vec3
+	major, minor float64
+}
+
+// Torus is a ring around c in the plane perpendicular to normal. The tube has
+// radius minor and its centre line radius major.
+func Torus(c, normal schematic.Vec3, major, minor float64) Shape {
+	return torus{c, normalize(normal), major, minor}
+}
+
+func (t torus) Bounds() (min, max [3]int) {
+	r := t.major + t.minor
+	return bounds(t.c.Sub(schematic.Vec3{X: r, Y: r, Z: r}), t.c.Add(schematic.Vec3{X: r, Y: r, Z: r}))
+}
+
+func (t torus) Contains(x, y, z int) bool {
+	q := vec(x, y, z).Sub(t.c)
+	h := q.Dot(t.n)
+	radial := q.Sub(t.n.Scale(h))
+	d := math.Sqrt(radial.Dot(radial)) - t.major
+	return d*d+h*h <= t.minor*t.minor+eps
+}
+
+type arch struct {
+	base         schematic.Vec3
+	along        schematic.Axis
+	outer, inner float64
+	lo, hi       [3]int
+}
+
+// Arch is a half ring standing on the ground at base, spanning along the X or
+// Z axis, with outer radius radius and the given thickness, extruded depth
+// blocks along the other horizontal axis.
+func Arch(base schematic.Vec3, along schematic.Axis, radius, thickness float64, depth int) Shape {
+	a := arch{base: base, along: along, outer: radius, inner: radius - thickness}
+	if along == schematic.AxisZ {
+		a.lo, a.hi = bounds(
+			schematic.Vec3{X: base.X, Y: base.Y, Z: base.Z - radius},
+			schematic.Vec3{X: base.X + float64(depth-1), Y: base.Y + radius, Z: base.Z + radius})
+	} else {
+		a.lo, a.hi = bounds(
+			schematic.Vec3{X: base.X - radius, Y: base.Y, Z: base.Z},
+			schematic.Vec3{X: base.X + radius, Y: base.Y + radius, Z: base.Z + float64(depth-1)})
+	}
+	return a
+}
+
+func (a arch) Bounds() (min, max [3]int) { return a.lo, a.hi }
+
+func (a arch) Contains(x, y, z int) bool {
+	if x < a.lo[0] || y < a.lo[1] || z < a.lo[2] || x > a.hi[0] || y > a.hi[1] || z > a.hi[2] {
+		return false
+	}
+	var u float64
+	if a.along == schematic.AxisZ {
+		u = float64(z) - a.base.Z
+	} else {
+		u = float64(x) - a.base.X
+	}
+	v := float64(y) - a.base.Y
+	d := u*u + v*v
+	return d <= a.outer*a.outer+eps && d >= a.inner*a.inner-eps
+}
+
+func vec(x, y, z int) schematic.Vec3 {
+	return schematic.Vec3{X: float64(x), Y: float64(y), Z: float64(z)}
+}
+
+func normalize(v schematic.Vec3) schematic.Vec3 {
+	l := math.Sqrt(v.Dot(v))
+	if l == 0 {
+		return schematic.Vec3{Y: 1}
+	}
+	return v.Scale(1 / l)
+}
+
+func bounds(lo, hi schematic.Vec3) (min, max [3]int) {
+	return [3]int{int(math.Floor(lo.X)), int(math.Floor(lo.Y)), int(math.Floor(lo.Z))},
+		[3]int{int(math.Ceil(hi.X)), int(math.Ceil(hi.Y)), int(math.Ceil(hi.Z))}
+}
+
+func sortCorners(a, b [3]int) (lo, hi [3]int) {
+	for i := 0; i < 3; i++ {
+		lo[i], hi[i] = minInt(a[i], b[i]), maxInt(a[i], b[i])
+	}
+	return lo, hi
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
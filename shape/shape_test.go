@@ -0,0 +1,90 @@
+package shape
+
+import (
+	"github.com/Tnze/go-mc/level/block"
+	"github.com/elvis972602/go-litematica-tools/schematic"
+	"testing"
+)
+
+var stone = schematic.Single(block.Stone{})
+
+func TestSphere(t *testing.T) {
+	p := schematic.NewProject("sphere", 11, 11, 11)
+	c := schematic.Vec3{X: 5, Y: 5, Z: 5}
+	filled := Draw(p, Sphere(c, 5), stone)
+	if filled != int(p.MetaData.TotalBlocks) {
+		t.Fatalf("Error, set %d blocks, total blocks %d", filled, p.MetaData.TotalBlocks)
+	}
+	for _, pos := range [][3]int{{0, 5, 5}, {10, 5, 5}, {5, 0, 5}, {5, 10, 5}, {5, 5, 0}, {5, 5, 10}} {
+		if p.GetBlock(pos[0], pos[1], pos[2]).Name == "minecraft:air" {
+			t.Fatalf("Error, pos %v should be in the sphere", pos)
+		}
+	}
+	if p.GetBlock(0, 0, 0).Name != "minecraft:air" {
+		t.Fatalf("Error, corner should be outside the sphere")
+	}
+
+	hollow := DrawHollow(schematic.NewProject("sphere", 11, 11, 11), Sphere(c, 5), stone)
+	if hollow >= filled || hollow == 0 {
+		t.Fatalf("Error, hollow sphere has %d blocks, filled %d", hollow, filled)
+	}
+}
+
+func TestCuboid(t *testing.T) {
+	p := schematic.NewProject("cuboid", 5, 4, 6)
+	if n := Draw(p, Cuboid([3]int{4, 3, 5}, [3]int{0, 0, 0}), stone); n != 5*4*6 {
+		t.Fatalf("Error, cuboid: %d", n)
+	}
+	if n := DrawHollow(schematic.NewProject("cuboid", 5, 4, 6), Cuboid([3]int{0, 0, 0}, [3]int{4, 3, 5}), stone); n != 5*4*6-3*2*4 {
+		t.Fatalf("Error, hollow cuboid: %d", n)
+	}
+	if n := Draw(schematic.NewProject("cuboid", 5, 4, 6), Walls([3]int{0, 0, 0}, [3]int{4, 3, 5}), stone); n != (5*6-3*4)*4 {
+		t.Fatalf("Error, walls: %d", n)
+	}
+}
+
+func TestLine(t *testing.T) {
+	pts := bresenham([3]int{0, 0, 0}, [3]int{7, -3, 2})
+	if len(pts) != 8 || pts[0] != [3]int{0, 0, 0} || pts[7] != [3]int{7, -3, 2} {
+		t.Fatalf("Error, bresenham: %v", pts)
+	}
+	for i := 1; i < len(pts); i++ {
+		for a := 0; a < 3; a++ {
+			if d := pts[i][a] - pts[i-1][a]; d < -1 || d > 1 {
+				t.Fatalf("Error, gap between %v and %v", pts[i-1], pts[i])
+			}
+		}
+	}
+
+	p := schematic.NewProject("line", 16, 16, 16)
+	if n := Draw(p, Line([3]int{2, 2, 2}, [3]int{12, 12, 2}, 3), stone); n <= 11 {
+		t.Fatalf("Error, thick line only has %d blocks", n)
+	}
+	// every step of thickness makes the line wider
+	width := func(thickness int) int {
+		lo, hi := Line([3]int{0, 0, 0}, [3]int{0, 0, 8}, thickness).Bounds()
+		return hi[0] - lo[0] + 1
+	}
+	for thickness, want := range []int{1, 1, 2, 3, 4} {
+		if w := width(thickness); w != want {
+			t.Fatalf("Error, line of thickness %d is %d wide, want %d", thickness, w, want)
+		}
+	}
+
+	curve := CubicBezier(schematic.Vec3{X: 0, Y: 0, Z: 8}, schematic.Vec3{X: 5, Y: 15, Z: 8},
+		schematic.Vec3{X: 10, Y: -5, Z: 8}, schematic.Vec3{X: 15, Y: 10, Z: 8}, 1)
+	if !curve.Contains(0, 0, 8) || !curve.Contains(15, 10, 8) {
+		t.Fatalf("Error, bezier tube doesn't reach its end points")
+	}
+}
+
+func TestTorusAndArch(t *testing.T) {
+	tor := Torus(schematic.Vec3{X: 8, Y: 8, Z: 8}, schematic.Vec3{Y: 1}, 5, 1.5)
+	if !tor.Contains(13, 8, 8) || tor.Contains(8, 8, 8) || tor.Contains(13, 10, 8) {
+		t.Fatalf("Error, torus membership is wrong")
+	}
+	a := Arch(schematic.Vec3{X: 8, Y: 0, Z: 0}, schematic.AxisX, 6, 2, 3)
+	if !a.Contains(2, 0, 0) || !a.Contains(8, 6, 2) || a.Contains(8, 2, 0) || a.Contains(8, 6, 3) {
+		t.Fatalf("Error, arch membership is wrong")
+	}
+}